@@ -1,13 +1,39 @@
 package main
 
-import "github.com/google/go-github/github"
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
 
 // API is the interface for interacting with the issue tracker
 type API interface {
 	Milestones(string) ([]*Milestone, error)
-	Search(string) <-chan *IssueResult
-	ByOrg(string) <-chan *IssueResult
-	ByUser() <-chan *IssueResult
+	// listing, a non-zero time means everything (including closed issues)
+	// updated since then rather than all open issues
+	Search(string, time.Time) <-chan *IssueResult
+	ByOrg(string, time.Time) <-chan *IssueResult
+	ByUser(time.Time) <-chan *IssueResult
+	Get(*Issue) (*github.Issue, error)
+	Comments(*Issue) ([]*Comment, error)
+	// Collaborators are the logins that can be assigned in a project
+	Collaborators(string) ([]string, error)
+	// OpenMilestones are all of a project's open milestones, tiers or not,
+	// and MilestoneIssues the open issues in one of them
+	OpenMilestones(string) ([]*Milestone, error)
+	MilestoneIssues(string, *Milestone) ([]github.Issue, error)
+
+	// mutations, a nil milestone removes the issue from its milestone
+	SetMilestone(*Issue, *Milestone) error
+	ReplaceLabels(*Issue, []string) error
+	AddLabel(*Issue, string) error
+	RemoveLabel(*Issue, string) error
+	SetState(*Issue, string) error
+	SetAssignees(*Issue, []string) error
+	AddComment(*Issue, string) error
+	CloseMilestone(string, *Milestone) error
 }
 
 // GithubAPI is the implementation of the issue tracker interface for Github
@@ -21,3 +47,217 @@ type GithubAPI struct {
 func NewGithubAPI(client *github.Client, opts *Options, config *Config) *GithubAPI {
 	return &GithubAPI{client, opts, config}
 }
+
+// MultiAPI sends each request to the GitHub, GitHub Enterprise or GitLab
+// host the project lives on, keyed by the host in the issue urls
+type MultiAPI struct {
+	apis        map[string]API
+	defaultHost string
+	config      *Config
+}
+
+// NewMultiAPI constructor, anything we can't place goes to defaultAPI
+func NewMultiAPI(defaultHost string, defaultAPI API, config *Config) *MultiAPI {
+	return &MultiAPI{map[string]API{defaultHost: defaultAPI}, defaultHost, config}
+}
+
+// Add the API for another host
+func (a *MultiAPI) Add(host string, api API) {
+	a.apis[host] = api
+}
+
+// hosts in a stable order, the default first
+func (a *MultiAPI) hosts() []string {
+	hosts := []string{}
+	for host := range a.apis {
+		if host != a.defaultHost {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return append([]string{a.defaultHost}, hosts...)
+}
+
+// isGitlab checks whether a project is one of our GitLab projects
+func (a *MultiAPI) isGitlab(project string) bool {
+	return a.config.IsGitlabProject(project)
+}
+
+// isGitlabGroup checks whether an org is the group of a GitLab project
+func (a *MultiAPI) isGitlabGroup(org string) bool {
+	for _, p := range a.config.Gitlab.Projects {
+		if strings.HasPrefix(p, org+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hostFor a project, either GitLab's, the one it is qualified with or
+// the default
+func (a *MultiAPI) hostFor(project string) string {
+	if a.isGitlab(project) {
+		return hostFromURL(a.config.Gitlab.URL)
+	}
+	if host, _ := splitProject(project); host != "" {
+		return host
+	}
+	return a.defaultHost
+}
+
+// api for a host, falling back to the default
+func (a *MultiAPI) api(host string) API {
+	if api, ok := a.apis[host]; ok {
+		return api
+	}
+	return a.apis[a.defaultHost]
+}
+
+// Milestones from whichever tracker hosts the project
+func (a *MultiAPI) Milestones(project string) ([]*Milestone, error) {
+	return a.api(a.hostFor(project)).Milestones(project)
+}
+
+// OpenMilestones from whichever tracker hosts the project
+func (a *MultiAPI) OpenMilestones(project string) ([]*Milestone, error) {
+	return a.api(a.hostFor(project)).OpenMilestones(project)
+}
+
+// MilestoneIssues from whichever tracker hosts the project
+func (a *MultiAPI) MilestoneIssues(project string, milestone *Milestone) ([]github.Issue, error) {
+	return a.api(a.hostFor(project)).MilestoneIssues(project, milestone)
+}
+
+// Search splits the repo: qualifiers in the query between the hosts,
+// everything else in the query goes to each of them
+func (a *MultiAPI) Search(query string, since time.Time) <-chan *IssueResult {
+	common := []string{}
+	repos := map[string][]string{}
+	for _, part := range strings.Fields(query) {
+		if !strings.HasPrefix(part, "repo:") {
+			common = append(common, part)
+			continue
+		}
+		project := strings.TrimPrefix(part, "repo:")
+		host := a.hostFor(project)
+		_, project = splitProject(project)
+		repos[host] = append(repos[host], "repo:"+project)
+	}
+
+	// with no repos at all it's a plain search on the default host
+	if len(repos) == 0 {
+		return a.api(a.defaultHost).Search(query, since)
+	}
+
+	chans := []<-chan *IssueResult{}
+	for _, host := range a.hosts() {
+		if len(repos[host]) == 0 {
+			continue
+		}
+		q := strings.Join(append(append([]string{}, common...), repos[host]...), " ")
+		chans = append(chans, a.api(host).Search(q, since))
+	}
+	return mergeResults(chans...)
+}
+
+// ByOrg goes to GitLab if we know of projects in a group by that name, or
+// to the host if the org is qualified with one like ghe.corp/team
+func (a *MultiAPI) ByOrg(org string, since time.Time) <-chan *IssueResult {
+	if a.isGitlabGroup(org) {
+		return a.api(hostFromURL(a.config.Gitlab.URL)).ByOrg(org, since)
+	}
+	if host, rest := splitProject(org + "/"); host != "" {
+		return a.api(host).ByOrg(strings.TrimSuffix(rest, "/"), since)
+	}
+	return a.api(a.defaultHost).ByOrg(org, since)
+}
+
+// ByUser combines the user's issues from every host
+func (a *MultiAPI) ByUser(since time.Time) <-chan *IssueResult {
+	chans := []<-chan *IssueResult{}
+	for _, host := range a.hosts() {
+		chans = append(chans, a.api(host).ByUser(since))
+	}
+	return mergeResults(chans...)
+}
+
+// Get from whichever tracker hosts the issue
+func (a *MultiAPI) Get(issue *Issue) (*github.Issue, error) {
+	return a.apiFor(issue).Get(issue)
+}
+
+// Collaborators from whichever tracker hosts the project
+func (a *MultiAPI) Collaborators(project string) ([]string, error) {
+	return a.api(a.hostFor(project)).Collaborators(project)
+}
+
+// Comments from whichever tracker hosts the issue
+func (a *MultiAPI) Comments(issue *Issue) ([]*Comment, error) {
+	return a.apiFor(issue).Comments(issue)
+}
+
+// apiFor picks the tracker an issue lives in by the host in its url
+func (a *MultiAPI) apiFor(issue *Issue) API {
+	return a.api(hostFromURL(issue.URL))
+}
+
+// SetMilestone on whichever tracker hosts the issue
+func (a *MultiAPI) SetMilestone(issue *Issue, milestone *Milestone) error {
+	return a.apiFor(issue).SetMilestone(issue, milestone)
+}
+
+// ReplaceLabels on whichever tracker hosts the issue
+func (a *MultiAPI) ReplaceLabels(issue *Issue, labels []string) error {
+	return a.apiFor(issue).ReplaceLabels(issue, labels)
+}
+
+// AddLabel on whichever tracker hosts the issue
+func (a *MultiAPI) AddLabel(issue *Issue, label string) error {
+	return a.apiFor(issue).AddLabel(issue, label)
+}
+
+// RemoveLabel on whichever tracker hosts the issue
+func (a *MultiAPI) RemoveLabel(issue *Issue, label string) error {
+	return a.apiFor(issue).RemoveLabel(issue, label)
+}
+
+// SetState on whichever tracker hosts the issue
+func (a *MultiAPI) SetState(issue *Issue, state string) error {
+	return a.apiFor(issue).SetState(issue, state)
+}
+
+// SetAssignees on whichever tracker hosts the issue
+func (a *MultiAPI) SetAssignees(issue *Issue, logins []string) error {
+	return a.apiFor(issue).SetAssignees(issue, logins)
+}
+
+// AddComment on whichever tracker hosts the issue
+func (a *MultiAPI) AddComment(issue *Issue, body string) error {
+	return a.apiFor(issue).AddComment(issue, body)
+}
+
+// CloseMilestone on whichever tracker hosts the project
+func (a *MultiAPI) CloseMilestone(project string, milestone *Milestone) error {
+	return a.api(a.hostFor(project)).CloseMilestone(project, milestone)
+}
+
+// mergeResults reads from each channel in turn into a single channel, it
+// stops sending after the first error but drains the rest so that nobody
+// is left blocked
+func mergeResults(chans ...<-chan *IssueResult) <-chan *IssueResult {
+	out := make(chan *IssueResult)
+	go func() {
+		defer close(out)
+		failed := false
+		for _, c := range chans {
+			for result := range c {
+				if failed {
+					continue
+				}
+				out <- result
+				failed = result.Err != nil
+			}
+		}
+	}()
+	return out
+}
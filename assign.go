@@ -0,0 +1,356 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/go-github/github"
+	"github.com/nsf/termbox-go"
+)
+
+// Collaborators on a project, everybody an issue can be assigned to
+func (a *GithubAPI) Collaborators(project string) ([]string, error) {
+	owner, repo, err := ownerRepo(project)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugln("Fetching collaborators for:", project)
+	params := &github.ListOptions{PerPage: 100}
+	logins := []string{}
+	for {
+		users, resp, err := a.client.Repositories.ListCollaborators(owner, repo, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			if user.Login != nil {
+				logins = append(logins, *user.Login)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		params.Page = resp.NextPage
+	}
+	return logins, nil
+}
+
+// Assigning is the state of the AssignWindow
+type Assigning struct {
+	Issues []*Issue
+	// Logins we can suggest, the collaborators on each issue's project
+	Logins []string
+	// Errs are the projects we couldn't get collaborators for
+	Errs   []string
+	Query  []rune
+	Cursor int
+	// Back is where to go when we're done
+	Back Window
+}
+
+// assign opens the AssignWindow for some issues, fetching the
+// collaborators of any project we haven't seen yet
+func (w *ListWindow) assign(issues []*Issue) {
+	if len(issues) == 0 {
+		return
+	}
+
+	a := &Assigning{Issues: issues, Back: w.Focus}
+	seen := map[string]bool{}
+	add := func(login string) {
+		if !seen[login] {
+			seen[login] = true
+			a.Logins = append(a.Logins, login)
+		}
+	}
+	for _, issue := range issues {
+		logins, ok := w.collaborators[issue.Project]
+		if !ok {
+			var err error
+			logins, err = w.API.Collaborators(issue.Project)
+			if err != nil {
+				logger.Errorln("Couldn't fetch collaborators for", issue.Project, err)
+				a.Errs = append(a.Errs, fmt.Sprintf("%s: %s", issue.Project, err))
+				// say so once per project, but try again next time
+				w.collaborators[issue.Project] = nil
+				defer delete(w.collaborators, issue.Project)
+			} else {
+				w.collaborators[issue.Project] = logins
+			}
+		}
+		for _, login := range logins {
+			add(login)
+		}
+		// whoever's assigned already can always be unassigned
+		for _, login := range issue.Assignees {
+			add(login)
+		}
+	}
+	sort.Strings(a.Logins)
+
+	w.assigning = a
+	w.Focus = w.Assign
+}
+
+// assigned is how many of the issues have login assigned
+func (a *Assigning) assigned(login string) int {
+	n := 0
+	for _, issue := range a.Issues {
+		if hasLogin(issue.Assignees, login) {
+			n++
+		}
+	}
+	return n
+}
+
+// matches for the query, best first, or everybody with the ones already
+// assigned first if there's no query
+func (a *Assigning) matches() []string {
+	query := strings.ToLower(string(a.Query))
+	scored := byScore{}
+	for _, login := range a.Logins {
+		if query == "" {
+			scored = append(scored, scoredLogin{login, -a.assigned(login)})
+			continue
+		}
+		if score, ok := fuzzyScore(query, strings.ToLower(login)); ok {
+			scored = append(scored, scoredLogin{login, score})
+		}
+	}
+	// the logins are sorted already, so ties stay alphabetical
+	sort.Stable(scored)
+	matches := []string{}
+	for _, s := range scored {
+		matches = append(matches, s.Login)
+	}
+	return matches
+}
+
+// scoredLogin is a login and how well it matches, lower is better
+type scoredLogin struct {
+	Login string
+	Score int
+}
+
+// byScore sorts logins best match first
+type byScore []scoredLogin
+
+// Len for Sortable
+func (ss byScore) Len() int {
+	return len(ss)
+}
+
+// Swap for Sortable
+func (ss byScore) Swap(i, j int) {
+	ss[i], ss[j] = ss[j], ss[i]
+}
+
+// Less for Sortable
+func (ss byScore) Less(i, j int) bool {
+	return ss[i].Score < ss[j].Score
+}
+
+// fuzzyScore checks that the letters of query appear in order in s, the
+// lower the score the closer together and nearer the start they are
+func fuzzyScore(query, s string) (int, bool) {
+	score := 0
+	last := -1
+	runes := []rune(s)
+	i := 0
+	for _, q := range query {
+		for i < len(runes) && runes[i] != q {
+			i++
+		}
+		if i == len(runes) {
+			return 0, false
+		}
+		if last < 0 {
+			score += i
+		} else {
+			score += i - last - 1
+		}
+		last = i
+		i++
+	}
+	return score, true
+}
+
+// hasLogin checks for a login, github logins aren't case sensitive
+func hasLogin(logins []string, login string) bool {
+	for _, l := range logins {
+		if strings.EqualFold(l, login) {
+			return true
+		}
+	}
+	return false
+}
+
+// toggle login on the issues, if they all have it it's taken off them all
+// and otherwise it's added to the ones that don't
+func (w *ListWindow) toggleAssignee(issues []*Issue, login string) {
+	remove := true
+	for _, issue := range issues {
+		if !hasLogin(issue.Assignees, login) {
+			remove = false
+		}
+	}
+
+	what := fmt.Sprintf("assignee +%s", login)
+	if remove {
+		what = fmt.Sprintf("assignee -%s", login)
+	}
+	w.batch(what, issues, func(issue *Issue) error {
+		assignees := []string{}
+		for _, l := range issue.Assignees {
+			if !strings.EqualFold(l, login) {
+				assignees = append(assignees, l)
+			}
+		}
+		if !remove {
+			assignees = append(assignees, login)
+		}
+		err := w.API.SetAssignees(issue, assignees)
+		if err != nil {
+			return err
+		}
+		issue.Assignees = assignees
+		return nil
+	})
+}
+
+// initials of the assignees for the list, one letter each, up to width
+func initials(assignees []string, width int) string {
+	out := []rune{}
+	for _, login := range assignees {
+		if len(out) == width {
+			out[width-1] = '+'
+			break
+		}
+		for _, r := range login {
+			out = append(out, unicode.ToUpper(r))
+			break
+		}
+	}
+	return string(out)
+}
+
+// AssignWindow picks assignees, typing narrows down the collaborators
+type AssignWindow struct {
+	*ListWindow
+}
+
+// NewAssignWindow ctor
+func NewAssignWindow(w *ListWindow) *AssignWindow {
+	return &AssignWindow{w}
+}
+
+// Init noop
+func (w *AssignWindow) Init() error {
+	return nil
+}
+
+// Draw the search and the logins that match it
+func (w *AssignWindow) Draw(x, y, x1, y1 int) {
+	a := w.assigning
+	if a == nil {
+		return
+	}
+	printLine(fmt.Sprintf("assignees for %s: [up/down] pick [enter] toggle [esc] done", describeIssues(a.Issues)), x+2, y)
+	search := fmt.Sprintf("search: %s", string(a.Query))
+	printLine(search, x+2, y+1)
+	screen.SetCursor(x+2+len([]rune(search)), y+1)
+
+	line := y + 2
+	for _, err := range a.Errs {
+		printLineColor(fmt.Sprintf("couldn't get collaborators for %s", err), x+2, line, termbox.Attribute(245), termbox.ColorDefault)
+		line++
+	}
+
+	matches := a.matches()
+	if len(matches) == 0 {
+		if len(a.Query) > 0 {
+			printLine(fmt.Sprintf("nobody matches, [enter] toggles %q anyway", string(a.Query)), x+2, line)
+		} else {
+			printLine("nobody to assign", x+2, line)
+		}
+		return
+	}
+
+	// keep the cursor on the screen
+	height := y1 - line + 1
+	top := 0
+	if a.Cursor >= height {
+		top = a.Cursor - height + 1
+	}
+	for i, login := range matches {
+		if i < top {
+			continue
+		}
+		if line > y1 {
+			break
+		}
+		cursor := " "
+		if i == a.Cursor {
+			cursor = ">"
+		}
+		check := " "
+		switch n := a.assigned(login); {
+		case n == len(a.Issues):
+			check = "x"
+		case n > 0:
+			check = "-"
+		}
+		printLine(fmt.Sprintf("%s [%s] %s", cursor, check, login), x+1, line)
+		line++
+	}
+}
+
+// HandleEvent searches, moves and toggles
+func (w *AssignWindow) HandleEvent(ev termbox.Event) (bool, error) {
+	a := w.assigning
+	if a == nil || ev.Type != termbox.EventKey {
+		return false, nil
+	}
+
+	matches := a.matches()
+	switch ev.Key {
+	case termbox.KeyEsc:
+		screen.HideCursor()
+		w.Focus = a.Back
+		w.ContextMenu = w.ListMenu
+		w.assigning = nil
+	case termbox.KeyArrowUp:
+		if a.Cursor > 0 {
+			a.Cursor--
+		}
+	case termbox.KeyArrowDown:
+		if a.Cursor < len(matches)-1 {
+			a.Cursor++
+		}
+	case termbox.KeyEnter:
+		login := string(a.Query)
+		if len(matches) > 0 {
+			login = matches[a.Cursor]
+		}
+		if login != "" {
+			w.toggleAssignee(a.Issues, login)
+		}
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(a.Query) > 0 {
+			a.Query = a.Query[:len(a.Query)-1]
+			a.Cursor = 0
+		}
+	case termbox.KeySpace:
+		// no spaces in logins
+	default:
+		if ev.Ch == 0 {
+			return false, nil
+		}
+		a.Query = append(a.Query, ev.Ch)
+		a.Cursor = 0
+	}
+	return true, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Cache is what we remember about a query between runs so we only have to
+// ask for what changed
+type Cache struct {
+	Key        string
+	LastSync   time.Time
+	Issues     []github.Issue
+	Milestones map[string][]*Milestone
+
+	path string
+}
+
+// cacheDir is $XDG_CACHE_HOME/triage, defaulting to ~/.cache/triage
+func cacheDir() string {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		base = filepath.Join(os.Getenv("HOME"), ".cache")
+	}
+	return filepath.Join(base, "triage")
+}
+
+// LoadCache for a query, a missing cache just means an empty one
+func LoadCache(key string) (*Cache, error) {
+	path := filepath.Join(cacheDir(), fmt.Sprintf("%x.json", sha1.Sum([]byte(key))))
+	cache := &Cache{Key: key, path: path}
+
+	ok, err := exists(path)
+	if err != nil || !ok {
+		return cache, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, cache)
+	if err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// Save the cache, a cache that wasn't loaded from anywhere isn't saved
+func (c *Cache) Save() error {
+	if c.path == "" {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(c.path), 0700)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// write then rename so a crash doesn't leave half a cache around
+	tmp := c.path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// Merge updated issues into the cache, replacing the ones we had and
+// dropping any that have been closed
+func (c *Cache) Merge(issues []github.Issue) {
+	updated := map[string]github.Issue{}
+	for _, issue := range issues {
+		updated[*issue.HTMLURL] = issue
+	}
+
+	merged := []github.Issue{}
+	for _, issue := range c.Issues {
+		if u, ok := updated[*issue.HTMLURL]; ok {
+			issue = u
+			delete(updated, *issue.HTMLURL)
+		}
+		if issue.State != nil && *issue.State == "closed" {
+			continue
+		}
+		merged = append(merged, issue)
+	}
+	// anything left is new to us
+	for _, issue := range issues {
+		if _, ok := updated[*issue.HTMLURL]; !ok {
+			continue
+		}
+		delete(updated, *issue.HTMLURL)
+		if issue.State != nil && *issue.State == "closed" {
+			continue
+		}
+		merged = append(merged, issue)
+	}
+	c.Issues = merged
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// cachedIssue in wercker/foo with a title and state
+func cachedIssue(number int, title, state string) github.Issue {
+	return github.Issue{
+		Number:  github.Int(number),
+		Title:   github.String(title),
+		State:   github.String(state),
+		HTMLURL: github.String(fmt.Sprintf("https://github.com/wercker/foo/issues/%d", number)),
+	}
+}
+
+func TestCacheMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		cached   []github.Issue
+		updated  []github.Issue
+		expected string
+	}{
+		{
+			name:     "first sync",
+			updated:  []github.Issue{cachedIssue(1, "One", "open"), cachedIssue(2, "Two", "open")},
+			expected: "1 One, 2 Two",
+		},
+		{
+			name:     "nothing changed",
+			cached:   []github.Issue{cachedIssue(1, "One", "open"), cachedIssue(2, "Two", "open")},
+			expected: "1 One, 2 Two",
+		},
+		{
+			name:     "updates replace in place and new ones go last",
+			cached:   []github.Issue{cachedIssue(1, "One", "open"), cachedIssue(2, "Two", "open")},
+			updated:  []github.Issue{cachedIssue(3, "Three", "open"), cachedIssue(1, "Uno", "open")},
+			expected: "1 Uno, 2 Two, 3 Three",
+		},
+		{
+			name:     "closed since the last sync",
+			cached:   []github.Issue{cachedIssue(1, "One", "open"), cachedIssue(2, "Two", "open")},
+			updated:  []github.Issue{cachedIssue(1, "One", "closed")},
+			expected: "2 Two",
+		},
+		{
+			name:     "opened and closed since the last sync",
+			cached:   []github.Issue{cachedIssue(1, "One", "open")},
+			updated:  []github.Issue{cachedIssue(2, "Two", "closed")},
+			expected: "1 One",
+		},
+		{
+			name:     "the same issue on two pages",
+			cached:   []github.Issue{cachedIssue(1, "One", "open")},
+			updated:  []github.Issue{cachedIssue(2, "Two", "open"), cachedIssue(2, "Two", "open")},
+			expected: "1 One, 2 Two",
+		},
+	}
+	for _, test := range tests {
+		cache := &Cache{Issues: test.cached}
+		cache.Merge(test.updated)
+		got := []string{}
+		for _, issue := range cache.Issues {
+			got = append(got, fmt.Sprintf("%d %s", *issue.Number, *issue.Title))
+		}
+		if strings.Join(got, ", ") != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, strings.Join(got, ", "))
+		}
+	}
+}
+
+func TestLoadCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triage-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	os.Setenv("XDG_CACHE_HOME", dir)
+
+	// nothing there yet
+	cache, err := LoadCache("org:wercker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Key != "org:wercker" || len(cache.Issues) != 0 || !cache.LastSync.IsZero() {
+		t.Fatalf("expected an empty cache, got %+v", cache)
+	}
+
+	synced := time.Date(2016, 3, 7, 12, 0, 0, 0, time.UTC)
+	cache.LastSync = synced
+	cache.Merge([]github.Issue{cachedIssue(1, "One", "open")})
+	cache.Milestones = map[string][]*Milestone{"wercker/foo": {{Number: 2, Title: "Next"}}}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadCache("org:wercker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.LastSync.Equal(synced) || len(loaded.Issues) != 1 || *loaded.Issues[0].Title != "One" || loaded.Milestones["wercker/foo"][0].Title != "Next" {
+		t.Errorf("expected what was saved, got %+v", loaded)
+	}
+
+	// every query gets its own
+	other, err := LoadCache("search:repo:wercker/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other.Issues) != 0 {
+		t.Errorf("expected another query's cache to be empty, got %+v", other)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "triage", "*.json"))
+	if len(files) != 1 {
+		t.Errorf("expected one cache file, got %v", files)
+	}
+
+	// a broken one is an error rather than an empty cache
+	if err := ioutil.WriteFile(files[0], []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCache("org:wercker"); err == nil {
+		t.Error("expected an error for a broken cache")
+	}
+
+	// one that wasn't loaded isn't saved
+	if err := (&Cache{Key: "user"}).Save(); err != nil {
+		t.Error(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "triage", "*")); len(files) != 1 {
+		t.Errorf("expected nothing new to be saved, got %v", files)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	tests := []struct {
+		org      string
+		target   string
+		expected string
+	}{
+		{"wercker", "", "org:wercker"},
+		{"wercker", "repo:wercker/foo", "org:wercker"},
+		{"", "repo:wercker/foo", "search:repo:wercker/foo"},
+		{"", "", "user"},
+	}
+	for _, test := range tests {
+		w := &TopIssueWindow{Org: test.org, Target: test.target}
+		if key := w.cacheKey(); key != test.expected {
+			t.Errorf("%q %q: expected %s, got %s", test.org, test.target, test.expected, key)
+		}
+	}
+}
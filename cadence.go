@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a Cadence worked out into actual dates
+type Schedule struct {
+	Days   int
+	Anchor time.Time
+	Loc    *time.Location
+}
+
+// Schedule for the cadence, filling in the defaults: weekly, due on
+// monday, in UTC
+func (c Cadence) Schedule() (*Schedule, error) {
+	loc := time.UTC
+	if c.Timezone != "" {
+		l, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("Unknown timezone: %s", c.Timezone)
+		}
+		loc = l
+	}
+
+	days, err := parseLength(c.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	weekday := time.Monday
+	if c.Weekday != "" {
+		weekday, err = parseWeekday(c.Weekday)
+		if err != nil {
+			return nil, err
+		}
+		if days%7 != 0 {
+			return nil, fmt.Errorf("A cadence of %d days won't stay on a %s, use whole weeks", days, weekday)
+		}
+	}
+
+	var anchor time.Time
+	if c.Anchor != "" {
+		anchor, err = time.ParseInLocation("2006-01-02", c.Anchor, loc)
+		if err != nil {
+			return nil, fmt.Errorf("Expected an anchor like 2016-01-04, got: %s", c.Anchor)
+		}
+		if c.Weekday != "" && anchor.Weekday() != weekday {
+			return nil, fmt.Errorf("The anchor %s is a %s, not a %s", c.Anchor, anchor.Weekday(), weekday)
+		}
+	} else {
+		// count from the first one in 1970 so everybody gets the same dates
+		anchor = time.Date(1970, 1, 1, 0, 0, 0, 0, loc)
+		for anchor.Weekday() != weekday {
+			anchor = anchor.AddDate(0, 0, 1)
+		}
+	}
+	return &Schedule{Days: days, Anchor: anchor, Loc: loc}, nil
+}
+
+// parseLength of a cadence like 7d, 2w or just a number of days
+func parseLength(length string) (int, error) {
+	s := strings.ToLower(strings.TrimSpace(length))
+	if s == "" {
+		return 7, nil
+	}
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(s, "w"):
+		multiplier = 7
+		s = strings.TrimSuffix(s, "w")
+	case strings.HasSuffix(s, "d"):
+		s = strings.TrimSuffix(s, "d")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("Expected a cadence length like 7d or 2w, got: %s", length)
+	}
+	return n * multiplier, nil
+}
+
+// parseWeekday like monday or mon
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || (len(s) >= 3 && strings.HasPrefix(name, s)) {
+			return d, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("Unknown weekday: %s", s)
+}
+
+// Next due date after t
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.Loc)
+	// count whole days on the calendar, DST makes some of them short
+	from := time.Date(s.Anchor.Year(), s.Anchor.Month(), s.Anchor.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(to.Sub(from).Hours() / 24)
+
+	n := days / s.Days
+	if days < 0 {
+		n--
+	}
+	due := s.Anchor.AddDate(0, 0, n*s.Days)
+	for !due.After(t) {
+		due = due.AddDate(0, 0, s.Days)
+	}
+	return due
+}
+
+// Upcoming are the due dates for the next count milestones, with a little
+// wiggle room so one made a couple of days early still gets a whole
+// cadence of its own
+func (s *Schedule) Upcoming(now time.Time, count int) []time.Time {
+	wiggle := s.Days - 2
+	if wiggle < 0 {
+		wiggle = 0
+	}
+	return s.From(s.Next(now.AddDate(0, 0, wiggle)), count)
+}
+
+// From is count due dates starting at first
+func (s *Schedule) From(first time.Time, count int) []time.Time {
+	dates := []time.Time{}
+	for i := 0; i < count; i++ {
+		dates = append(dates, first.AddDate(0, 0, i*s.Days))
+	}
+	return dates
+}
+
+// Title for a milestone due on date, from the week it's due unless the
+// cadence fits more than one milestone in a week
+func (s *Schedule) Title(date time.Time) string {
+	if s.Days < 7 {
+		return dayTitle(date)
+	}
+	return weekTitle(date)
+}
+
+// weekTitle is the title for a milestone due on date, the same date always
+// gets the same fancy name
+func weekTitle(date time.Time) string {
+	year, week := date.ISOWeek()
+	seed, _ := strconv.Atoi(fmt.Sprintf("%d%02d", year, week))
+	return fmt.Sprintf("%d-%02d %s", year, week, shipName(seed))
+}
+
+// dayTitle is like weekTitle but from the day, for short cadences
+func dayTitle(date time.Time) string {
+	seed, _ := strconv.Atoi(date.Format("20060102"))
+	return fmt.Sprintf("%s %s", date.Format("2006-01-02"), shipName(seed))
+}
+
+// shipName picks one of the Titles
+func shipName(seed int) string {
+	return Titles[rand.New(rand.NewSource(int64(seed))).Intn(len(Titles))]
+}
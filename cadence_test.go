@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	weekly, err := Cadence{}.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// across both DST changes in 2016
+	fortnightly, err := Cadence{Length: "2w", Weekday: "thu", Timezone: "America/Los_Angeles", Anchor: "2016-03-10"}.Schedule()
+	if err != nil {
+		t.Skip("no timezone data:", err)
+	}
+	la := fortnightly.Loc
+
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		now      time.Time
+		expected string
+	}{
+		{"weekly", weekly, time.Date(2016, 3, 2, 12, 0, 0, 0, time.UTC), "2016-03-07"},
+		{"weekly on the day", weekly, time.Date(2016, 3, 7, 0, 0, 0, 0, time.UTC), "2016-03-14"},
+		{"before the anchor", fortnightly, time.Date(2016, 3, 1, 12, 0, 0, 0, la), "2016-03-10"},
+		{"on the anchor", fortnightly, time.Date(2016, 3, 10, 0, 0, 0, 0, la), "2016-03-24"},
+		{"into summer time", fortnightly, time.Date(2016, 3, 12, 23, 0, 0, 0, la), "2016-03-24"},
+		{"late on the day before", fortnightly, time.Date(2016, 11, 2, 23, 59, 0, 0, la), "2016-11-03"},
+		{"out of summer time", fortnightly, time.Date(2016, 11, 4, 0, 0, 0, 0, la), "2016-11-17"},
+		// still wednesday in los angeles
+		{"another timezone", fortnightly, time.Date(2016, 11, 3, 6, 0, 0, 0, time.UTC), "2016-11-03"},
+	}
+	for _, test := range tests {
+		due := test.schedule.Next(test.now)
+		if due.Format("2006-01-02") != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, due)
+		}
+		if due.Location() != test.schedule.Loc || due.Hour() != 0 || due.Minute() != 0 {
+			t.Errorf("%s: expected midnight in %s, got %s", test.name, test.schedule.Loc, due)
+		}
+	}
+}
+
+func TestScheduleUpcoming(t *testing.T) {
+	tests := []struct {
+		cadence  Cadence
+		now      time.Time
+		expected string
+	}{
+		// a couple of days early still gets a whole week
+		{Cadence{}, time.Date(2016, 3, 2, 12, 0, 0, 0, time.UTC), "2016-03-14 2016-03-21 2016-03-28"},
+		{Cadence{}, time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC), "2016-03-07 2016-03-14 2016-03-21"},
+		{Cadence{Length: "2w", Anchor: "2016-03-07"}, time.Date(2016, 3, 2, 12, 0, 0, 0, time.UTC), "2016-03-21 2016-04-04 2016-04-18"},
+		{Cadence{Length: "3d", Anchor: "2016-03-01"}, time.Date(2016, 3, 2, 12, 0, 0, 0, time.UTC), "2016-03-04 2016-03-07 2016-03-10"},
+		{Cadence{Length: "1"}, time.Date(2016, 3, 2, 12, 0, 0, 0, time.UTC), "2016-03-03 2016-03-04 2016-03-05"},
+	}
+	for _, test := range tests {
+		schedule, err := test.cadence.Schedule()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dates := []string{}
+		for _, date := range schedule.Upcoming(test.now, 3) {
+			dates = append(dates, date.Format("2006-01-02"))
+		}
+		if got := strings.Join(dates, " "); got != test.expected {
+			t.Errorf("%+v: expected %s, got %s", test.cadence, test.expected, got)
+		}
+	}
+}
+
+func TestCadenceErrors(t *testing.T) {
+	for _, cadence := range []Cadence{
+		{Length: "fortnight"},
+		{Length: "0d"},
+		{Length: "10d", Weekday: "monday"},
+		{Weekday: "someday"},
+		{Timezone: "Mars/Olympus_Mons"},
+		{Anchor: "2016-03-32"},
+		{Weekday: "monday", Anchor: "2016-03-10"},
+	} {
+		if _, err := cadence.Schedule(); err == nil {
+			t.Errorf("expected an error for %+v", cadence)
+		}
+	}
+}
+
+func TestScheduleTitle(t *testing.T) {
+	weekly, _ := Cadence{}.Schedule()
+	if title := weekly.Title(time.Date(2016, 3, 7, 0, 0, 0, 0, time.UTC)); !strings.HasPrefix(title, "2016-10 ") {
+		t.Errorf("expected a title from the week, got %s", title)
+	}
+	if weekly.Title(time.Date(2016, 3, 7, 0, 0, 0, 0, time.UTC)) != weekTitle(time.Date(2016, 3, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the same title all week")
+	}
+
+	// three milestones can be due in the same week
+	short, _ := Cadence{Length: "2d", Anchor: "2016-03-01"}.Schedule()
+	seen := map[string]bool{}
+	for _, date := range short.From(time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC), 4) {
+		title := short.Title(date)
+		if !strings.HasPrefix(title, date.Format("2006-01-02")+" ") || seen[title] {
+			t.Errorf("expected a new title from the day for %s, got %s", date, title)
+		}
+		seen[title] = true
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/nsf/termbox-go"
+)
+
+// AddComment to an issue
+func (a *GithubAPI) AddComment(issue *Issue, body string) error {
+	_, _, err := a.client.Issues.CreateComment(issue.Owner, issue.Repo, issue.Number, &github.IssueComment{Body: &body})
+	return err
+}
+
+// ListCommentMenu for commenting, "c" again writes one and the replies
+// post theirs
+type ListCommentMenu struct {
+	*ListWindow
+}
+
+// NewListCommentMenu ctor
+func NewListCommentMenu(w *ListWindow) *ListCommentMenu {
+	return &ListCommentMenu{w}
+}
+
+// Init noop
+func (w *ListCommentMenu) Init() error {
+	return nil
+}
+
+// Draw the menu
+func (w *ListCommentMenu) Draw(x, y, x1, y1 int) {
+	if w.Focus != w.List {
+		return
+	}
+
+	printLine(fmt.Sprintf("comment: [c] write one%s", w.repliesMenu()), x+2, y)
+}
+
+// repliesMenu lists the canned replies and their hotkeys
+func (w *ListWindow) repliesMenu() string {
+	menu := ""
+	for _, reply := range w.Config.Replies {
+		menu += fmt.Sprintf(" [%s] %s", reply.Hotkey, reply.Name)
+	}
+	return menu
+}
+
+// HandleEvent posts a canned reply on the marked issues, or the current one
+func (w *ListCommentMenu) HandleEvent(ev termbox.Event) (bool, error) {
+	issues := w.targets()
+	return w.replyEvent(ev, issues, len(issues) > 1), nil
+}
+
+// replyEvent posts the reply with the hotkey pressed, if there is one, or
+// opens it as a draft first, comments can't be undone
+func (w *ListWindow) replyEvent(ev termbox.Event, issues []*Issue, draft bool) bool {
+	for _, reply := range w.Config.Replies {
+		if ev.Ch != 0 && ev.Ch == reply.Key() {
+			if draft {
+				w.compose(issues, reply.Body)
+				return true
+			}
+			w.postComment(issues, reply.Body)
+			return true
+		}
+	}
+	return false
+}
+
+// postComment on each issue
+func (w *ListWindow) postComment(issues []*Issue, body string) {
+	body = strings.TrimRight(body, " \t\r\n")
+	if strings.TrimSpace(body) == "" {
+		w.notice = "empty comment, nothing posted"
+		return
+	}
+	posted := []*Issue{}
+	w.each("Commenting", issues, func(issue *Issue) error {
+		err := w.API.AddComment(issue, body)
+		if err != nil {
+			return err
+		}
+		// so the detail window knows to fetch the comments again
+		issue.UpdatedAt = time.Now()
+		posted = append(posted, issue)
+		return nil
+	})
+	if len(posted) > 0 {
+		w.notice = fmt.Sprintf("commented on %s", describeIssues(posted))
+	}
+}
+
+// compose a comment for some issues, in $VISUAL or $EDITOR if there is one
+// and otherwise in the ComposeWindow
+func (w *ListWindow) compose(issues []*Issue, text string) {
+	if len(issues) == 0 {
+		return
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		w.draft = &Draft{Issues: issues, Text: []rune(text), Cursor: len([]rune(text)), Back: w.Focus}
+		w.Focus = w.Compose
+		return
+	}
+
+	body, err := editComment(editor, text)
+	if err != nil {
+		logger.Errorln("Couldn't edit comment:", err)
+		w.Alert = fmt.Sprintf("Couldn't edit comment: %s", err)
+		w.Focus = w.AlertModal
+		return
+	}
+	// quitting the editor on a canned reply shouldn't send it anyway
+	if text != "" && strings.TrimSpace(body) == strings.TrimSpace(text) {
+		w.notice = "reply unchanged, nothing posted"
+		return
+	}
+	w.postComment(issues, body)
+}
+
+// editComment runs the editor on a temp file holding text, and returns
+// what was saved
+func editComment(editor, text string) (string, error) {
+	f, err := ioutil.TempFile("", "triage-comment-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	// the editor can come with arguments, like "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = screen.Suspend(cmd.Run)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Draft is a comment being written in the ComposeWindow
+type Draft struct {
+	Issues []*Issue
+	Text   []rune
+	Cursor int
+	// Back is where to go when it's posted or thrown away
+	Back Window
+}
+
+// ComposeWindow is a small multi-line editor for comments, for when
+// there's no $EDITOR
+type ComposeWindow struct {
+	*ListWindow
+}
+
+// NewComposeWindow ctor
+func NewComposeWindow(w *ListWindow) *ComposeWindow {
+	return &ComposeWindow{w}
+}
+
+// Init noop
+func (w *ComposeWindow) Init() error {
+	return nil
+}
+
+// Draw the draft, wrapped at the edge, with the cursor in it
+func (w *ComposeWindow) Draw(x, y, x1, y1 int) {
+	if w.draft == nil {
+		return
+	}
+	printLine(fmt.Sprintf("comment on %s: [ctrl-s] post [esc] cancel", describeIssues(w.draft.Issues)), x+2, y)
+
+	width := x1 - x - 3
+	if width < 1 {
+		width = 1
+	}
+	height := y1 - y
+
+	// lay out every rune, then scroll so the cursor is on screen
+	type cell struct {
+		ch       rune
+		col, row int
+	}
+	cells := []cell{}
+	col, row := 0, 0
+	cursorCol, cursorRow := 0, 0
+	for i, c := range w.draft.Text {
+		if col >= width {
+			col, row = 0, row+1
+		}
+		if i == w.draft.Cursor {
+			cursorCol, cursorRow = col, row
+		}
+		if c == '\n' {
+			col, row = 0, row+1
+			continue
+		}
+		cells = append(cells, cell{c, col, row})
+		col++
+	}
+	if w.draft.Cursor >= len(w.draft.Text) {
+		if col >= width {
+			col, row = 0, row+1
+		}
+		cursorCol, cursorRow = col, row
+	}
+
+	top := 0
+	if cursorRow >= height {
+		top = cursorRow - height + 1
+	}
+	for _, c := range cells {
+		if c.row < top || c.row-top >= height {
+			continue
+		}
+		screen.SetCell(x+2+c.col, y+1+c.row-top, c.ch, termbox.ColorDefault, termbox.ColorDefault)
+	}
+	screen.SetCursor(x+2+cursorCol, y+1+cursorRow-top)
+
+	w.Status += fmt.Sprintf("%d characters ", len(w.draft.Text))
+}
+
+// HandleEvent edits the draft
+func (w *ComposeWindow) HandleEvent(ev termbox.Event) (bool, error) {
+	d := w.draft
+	if d == nil || ev.Type != termbox.EventKey {
+		return false, nil
+	}
+
+	switch ev.Key {
+	case termbox.KeyEsc:
+		w.closeDraft()
+		w.notice = "comment thrown away"
+	case termbox.KeyCtrlS:
+		w.closeDraft()
+		w.postComment(d.Issues, string(d.Text))
+	case termbox.KeyEnter:
+		d.insert('\n')
+	case termbox.KeySpace:
+		d.insert(' ')
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if d.Cursor > 0 {
+			d.Text = append(d.Text[:d.Cursor-1], d.Text[d.Cursor:]...)
+			d.Cursor--
+		}
+	case termbox.KeyDelete:
+		if d.Cursor < len(d.Text) {
+			d.Text = append(d.Text[:d.Cursor], d.Text[d.Cursor+1:]...)
+		}
+	case termbox.KeyArrowLeft:
+		if d.Cursor > 0 {
+			d.Cursor--
+		}
+	case termbox.KeyArrowRight:
+		if d.Cursor < len(d.Text) {
+			d.Cursor++
+		}
+	case termbox.KeyArrowUp:
+		d.moveLine(-1)
+	case termbox.KeyArrowDown:
+		d.moveLine(1)
+	case termbox.KeyHome, termbox.KeyCtrlA:
+		d.Cursor = d.lineStart(d.Cursor)
+	case termbox.KeyEnd, termbox.KeyCtrlE:
+		d.Cursor = d.lineEnd(d.Cursor)
+	default:
+		if ev.Ch == 0 {
+			return false, nil
+		}
+		d.insert(ev.Ch)
+	}
+	return true, nil
+}
+
+// closeDraft and go back to where we were
+func (w *ComposeWindow) closeDraft() {
+	screen.HideCursor()
+	w.Focus = w.draft.Back
+	w.ContextMenu = w.ListMenu
+	w.draft = nil
+}
+
+// insert a rune at the cursor
+func (d *Draft) insert(c rune) {
+	d.Text = append(d.Text[:d.Cursor], append([]rune{c}, d.Text[d.Cursor:]...)...)
+	d.Cursor++
+}
+
+// lineStart is where the line with i in it starts
+func (d *Draft) lineStart(i int) int {
+	for i > 0 && d.Text[i-1] != '\n' {
+		i--
+	}
+	return i
+}
+
+// lineEnd is where the line with i in it ends, at its newline or the end
+func (d *Draft) lineEnd(i int) int {
+	for i < len(d.Text) && d.Text[i] != '\n' {
+		i++
+	}
+	return i
+}
+
+// moveLine moves the cursor up or down a line, keeping the column if the
+// line is long enough
+func (d *Draft) moveLine(by int) {
+	start := d.lineStart(d.Cursor)
+	col := d.Cursor - start
+	switch {
+	case by < 0:
+		if start == 0 {
+			return
+		}
+		start = d.lineStart(start - 1)
+	case by > 0:
+		end := d.lineEnd(d.Cursor)
+		if end == len(d.Text) {
+			return
+		}
+		start = end + 1
+	}
+	d.Cursor = start + col
+	if end := d.lineEnd(start); d.Cursor > end {
+		d.Cursor = end
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditedReplies(t *testing.T) {
+	defer func() { screen = termboxScreen{} }()
+	dir, err := ioutil.TempDir("", "triage-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// an editor that adds a line, and one that quits without saving
+	appender := filepath.Join(dir, "append")
+	err = ioutil.WriteFile(appender, []byte("#!/bin/sh\nprintf '\\nThanks!\\n' >> \"$1\"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("VISUAL", os.Getenv("VISUAL"))
+
+	tests := []struct {
+		editor string
+		keys   string
+		calls  string
+	}{
+		{"true", "<space><down><space>c1", ""},
+		{"true", "<enter>1", ""},
+		{appender, "<space><down><space>c1", "AddComment wercker/bar#40 Could you attach the logs?\\nThanks!; AddComment wercker/foo#12 Could you attach the logs?\\nThanks!"},
+		{appender, "<enter>1", "AddComment wercker/bar#40 Could you attach the logs?\\nThanks!"},
+		// one issue and no detail view means it goes straight out
+		{"true", "c1", "AddComment wercker/bar#40 Could you attach the logs?"},
+	}
+	for _, test := range tests {
+		os.Setenv("VISUAL", test.editor)
+		api, err := LoadFakeAPI(filepath.Join("testdata", "raw_issues.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, _ := testUI(t, api)
+		press(t, w, test.keys)
+		if calls := strings.Join(api.Calls, "; "); calls != test.calls {
+			t.Errorf("%s %q: expected %q, got %q", test.editor, test.keys, test.calls, calls)
+		}
+	}
+}
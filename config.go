@@ -1,8 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"gopkg.in/yaml.v2"
 )
@@ -10,10 +16,101 @@ import (
 // Projects are the list of projects we will care about by default
 type Projects []string
 
-// Label is a name and a color
+// Label is a name and a color, plus other names issues might have it under
 type Label struct {
-	Name  string `yaml:"name,omitempty"`
-	Color string `yaml:"color,omitempty"`
+	Name        string   `yaml:"name,omitempty"`
+	Color       string   `yaml:"color,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Aliases     []string `yaml:"aliases,omitempty"`
+}
+
+// Matches checks whether a label on an issue is this label, by name or
+// alias, ignoring case like github does
+func (l Label) Matches(name string) bool {
+	if strings.EqualFold(l.Name, name) {
+		return true
+	}
+	for _, alias := range l.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// priorityLabels are the priorities as plain labels
+func priorityLabels(ps []Priority) []Label {
+	labels := []Label{}
+	for _, p := range ps {
+		labels = append(labels, Label(p))
+	}
+	return labels
+}
+
+// typeLabels are the types as plain labels
+func typeLabels(ts []Type) []Label {
+	labels := []Label{}
+	for _, t := range ts {
+		labels = append(labels, Label(t))
+	}
+	return labels
+}
+
+// Dimension is a set of labels an issue has at most one of, like its
+// priority or type
+type Dimension struct {
+	Name string `yaml:"name"`
+	// Hotkey opens its menu in the ui and prefixes its filter token,
+	// defaults to the first letter of the name nothing else is using
+	Hotkey string  `yaml:"hotkey,omitempty"`
+	Labels []Label `yaml:"labels"`
+	// Idx adds its number to the idx the issues are sorted by
+	Idx bool `yaml:"idx,omitempty"`
+}
+
+// Key is the hotkey as a rune
+func (d *Dimension) Key() rune {
+	return firstRune(d.Hotkey)
+}
+
+// MilestoneTier is a milestone issues can be put in, either the nth
+// upcoming one by due date or one without a due date found by title
+type MilestoneTier struct {
+	Name string `yaml:"name"`
+	// Dated tiers are filled in order of upcoming due dates
+	Dated bool `yaml:"dated,omitempty"`
+	// Title of an undated tier's milestone, defaults to the name
+	Title string `yaml:"title,omitempty"`
+	// Hotkey in the milestone menu, defaults to its position
+	Hotkey string `yaml:"hotkey,omitempty"`
+	// Index is its number in the idx, defaults to its position
+	Index int `yaml:"index,omitempty"`
+}
+
+// Key is the hotkey as a rune
+func (t *MilestoneTier) Key() rune {
+	return firstRune(t.Hotkey)
+}
+
+// Reply is a canned comment, posted from the comment menu with its hotkey
+type Reply struct {
+	Name string `yaml:"name"`
+	Body string `yaml:"body"`
+	// Hotkey in the comment menu, defaults to its position
+	Hotkey string `yaml:"hotkey,omitempty"`
+}
+
+// Key is the hotkey as a rune
+func (r *Reply) Key() rune {
+	return firstRune(r.Hotkey)
+}
+
+// firstRune of a string, 0 if it's empty
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
 }
 
 // Priority probably doesn't need to be its own type
@@ -22,6 +119,34 @@ type Priority Label
 // Type probably doesn't need to be its own type
 type Type Label
 
+// GitlabConfig is where to find a GitLab instance and which projects to
+// triage there instead of on GitHub
+type GitlabConfig struct {
+	URL      string   `yaml:"url,omitempty"`
+	Projects Projects `yaml:"projects,omitempty"`
+}
+
+// GithubHost is a GitHub Enterprise install that some of the projects
+// live on, by default the api is at https://<host>/api/v3/ and the token
+// is the same as for github.com
+type GithubHost struct {
+	URL      string `yaml:"url,omitempty"`
+	TokenEnv string `yaml:"token-env,omitempty"`
+}
+
+// Cadence is how often milestones are due, for create-milestone
+type Cadence struct {
+	// Length between due dates, like 7d or 2w, a week by default
+	Length string `yaml:"length,omitempty"`
+	// Weekday they're due on, monday by default
+	Weekday string `yaml:"weekday,omitempty"`
+	// Timezone the due dates are in, UTC by default
+	Timezone string `yaml:"timezone,omitempty"`
+	// Anchor is any one due date, YYYY-MM-DD, so longer cadences land on
+	// the right weeks
+	Anchor string `yaml:"anchor,omitempty"`
+}
+
 // Config is our main config struct
 type Config struct {
 	NextMilestone    string `yaml:"next-milestone,omitempty"`
@@ -29,6 +154,175 @@ type Config struct {
 	Projects         Projects
 	Priorities       []Priority
 	Types            []Type
+	// Dimensions besides priority and type, or replacing them if they
+	// have the same name
+	Dimensions []Dimension `yaml:"dimensions,omitempty"`
+	// MilestoneTiers replace Current, Next and Someday
+	MilestoneTiers []MilestoneTier `yaml:"milestone-tiers,omitempty"`
+	Cadence        Cadence         `yaml:"cadence,omitempty"`
+	Gitlab         GitlabConfig    `yaml:"gitlab,omitempty"`
+	// GithubURL is the api url for projects that don't name a host
+	GithubURL   string                `yaml:"github-url,omitempty"`
+	GithubHosts map[string]GithubHost `yaml:"github-hosts,omitempty"`
+	// Renames are old label names and what they're called now, for
+	// `set-labels --migrate`
+	Renames map[string]string `yaml:"renames,omitempty"`
+	// KeepLabels are never deleted by `set-labels --prune`
+	KeepLabels []string `yaml:"keep-labels,omitempty"`
+	// NormalizeAliases swaps an aliased label for the real one when you
+	// set a priority or type, rather than leaving it as is, it's a pointer
+	// so a later layer can turn it back off
+	NormalizeAliases *bool `yaml:"normalize-aliases,omitempty"`
+	// Replies are canned comments for the comment menu
+	Replies []Reply `yaml:"replies,omitempty"`
+}
+
+// GithubHost is the web host of the default github, github.com unless
+// github-url points somewhere else
+func (c *Config) GithubHost() string {
+	if c.GithubURL == "" {
+		return "github.com"
+	}
+	return hostFromURL(c.GithubURL)
+}
+
+// OtherGithubHosts are the hosts besides the default one that we have
+// projects or config for, in a stable order
+func (c *Config) OtherGithubHosts() []string {
+	seen := map[string]bool{c.GithubHost(): true}
+	hosts := []string{}
+	add := func(host string) {
+		if host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	for _, project := range c.Projects {
+		host, _ := splitProject(project)
+		add(host)
+	}
+	names := []string{}
+	for host := range c.GithubHosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+	for _, host := range names {
+		add(host)
+	}
+	return hosts
+}
+
+// Dimension by name, nil if we don't have it
+func (c *Config) Dimension(name string) *Dimension {
+	for i := range c.Dimensions {
+		if strings.EqualFold(c.Dimensions[i].Name, name) {
+			return &c.Dimensions[i]
+		}
+	}
+	return nil
+}
+
+// IdxWidth is how many digits an idx has, one for the milestone and one
+// for each dimension in it
+func (c *Config) IdxWidth() int {
+	width := 1
+	for _, d := range c.Dimensions {
+		if d.Idx {
+			width++
+		}
+	}
+	return width
+}
+
+// Labels are the labels of every dimension
+func (c *Config) Labels() []Label {
+	labels := []Label{}
+	for _, d := range c.Dimensions {
+		labels = append(labels, d.Labels...)
+	}
+	return labels
+}
+
+// setDimensions puts priority and type in front of the configured
+// dimensions, unless they're configured there, and fills in hotkeys
+func (c *Config) setDimensions() {
+	dimensions := []Dimension{}
+	if c.Dimension("priority") == nil {
+		dimensions = append(dimensions, Dimension{Name: "priority", Hotkey: "p", Labels: priorityLabels(c.Priorities), Idx: true})
+	}
+	if c.Dimension("type") == nil {
+		dimensions = append(dimensions, Dimension{Name: "type", Hotkey: "t", Labels: typeLabels(c.Types), Idx: true})
+	}
+	c.Dimensions = append(dimensions, c.Dimensions...)
+
+	// the ui checks its own keys first, so "size" can't have "s"
+	taken := map[rune]bool{}
+	for _, d := range c.Dimensions {
+		if d.Hotkey != "" {
+			taken[d.Key()] = true
+		}
+	}
+	for i := range c.Dimensions {
+		d := &c.Dimensions[i]
+		if d.Hotkey != "" || d.Name == "" {
+			continue
+		}
+		for _, r := range strings.ToLower(d.Name) {
+			if unicode.IsLetter(r) && !taken[r] && !strings.ContainsRune(reservedKeys, r) {
+				d.Hotkey = string(r)
+				taken[r] = true
+				break
+			}
+		}
+	}
+}
+
+// setMilestoneTiers defaults to Current, Next and Someday and fills in
+// titles, hotkeys and indexes
+func (c *Config) setMilestoneTiers() {
+	if len(c.MilestoneTiers) == 0 {
+		c.MilestoneTiers = []MilestoneTier{
+			{Name: "current", Dated: true},
+			{Name: "next", Title: c.NextMilestone},
+			{Name: "someday", Title: c.SomedayMilestone},
+		}
+	}
+	for i := range c.MilestoneTiers {
+		tier := &c.MilestoneTiers[i]
+		if !tier.Dated && tier.Title == "" {
+			tier.Title = tier.Name
+		}
+		if tier.Hotkey == "" {
+			tier.Hotkey = strconv.Itoa(i + 1)
+		}
+		if tier.Index == 0 {
+			tier.Index = i + 1
+		}
+	}
+}
+
+// AllProjects are the GitHub and GitLab projects together
+func (c *Config) AllProjects() Projects {
+	projects := Projects{}
+	projects = append(projects, c.Projects...)
+	projects = append(projects, c.Gitlab.Projects...)
+	return projects
+}
+
+// ShouldNormalizeAliases is whether normalize-aliases is on, it's off
+// unless some layer turns it on
+func (c *Config) ShouldNormalizeAliases() bool {
+	return c.NormalizeAliases != nil && *c.NormalizeAliases
+}
+
+// IsGitlabProject checks whether a project is one of our GitLab projects
+func (c *Config) IsGitlabProject(project string) bool {
+	for _, p := range c.Gitlab.Projects {
+		if p == project {
+			return true
+		}
+	}
+	return false
 }
 
 // DefaultPriorities if none are specified in the config
@@ -53,27 +347,158 @@ var DefaultNextMilestone = "Next"
 // DefaultSomedayMilestone if none is specified in the config
 var DefaultSomedayMilestone = "Someday"
 
-// LoadConfig is the entrypoint into the config
-func LoadConfig(opts *Options) (*Config, error) {
+// configPaths are the config files to layer on top of each other, the
+// user's, then the nearest .triage/config.yml, then the one we were told
+// to use (or triage.yml in the current directory)
+func configPaths(explicit string) ([]string, error) {
+	paths := []string{}
 
-	var config Config
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		base = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	user := filepath.Join(base, "triage", "config.yml")
+	if ok, err := exists(user); err != nil {
+		return nil, err
+	} else if ok {
+		paths = append(paths, user)
+	}
 
-	// TODO(termie): make an option
-	f, err := os.Open("triage.yml")
-	if err == nil {
-		defer f.Close()
+	// walk up until we find a .triage directory
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		repo := filepath.Join(dir, ".triage", "config.yml")
+		if ok, err := exists(repo); err != nil {
+			return nil, err
+		} else if ok {
+			paths = append(paths, repo)
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
 
-		data, err := ioutil.ReadAll(f)
-		if err != nil {
+	if explicit != "" {
+		if ok, err := exists(explicit); err != nil {
 			return nil, err
+		} else if !ok {
+			return nil, fmt.Errorf("Config file not found: %s", explicit)
 		}
+		paths = append(paths, explicit)
+	} else if ok, _ := exists("triage.yml"); ok {
+		paths = append(paths, "triage.yml")
+	}
+	return paths, nil
+}
+
+// readConfig parses a single config file
+func readConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	err = yaml.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return config, nil
+}
 
-		err = yaml.Unmarshal(data, &config)
+// merge another layer of config into this one, anything it sets replaces
+// what we had
+func (c *Config) merge(layer *Config) {
+	if layer.NextMilestone != "" {
+		c.NextMilestone = layer.NextMilestone
+	}
+	if layer.SomedayMilestone != "" {
+		c.SomedayMilestone = layer.SomedayMilestone
+	}
+	if len(layer.Projects) > 0 {
+		c.Projects = layer.Projects
+	}
+	if len(layer.Priorities) > 0 {
+		c.Priorities = layer.Priorities
+	}
+	if len(layer.Types) > 0 {
+		c.Types = layer.Types
+	}
+	if len(layer.Dimensions) > 0 {
+		c.Dimensions = layer.Dimensions
+	}
+	if len(layer.MilestoneTiers) > 0 {
+		c.MilestoneTiers = layer.MilestoneTiers
+	}
+	if layer.Cadence.Length != "" {
+		c.Cadence.Length = layer.Cadence.Length
+	}
+	if layer.Cadence.Weekday != "" {
+		c.Cadence.Weekday = layer.Cadence.Weekday
+	}
+	if layer.Cadence.Timezone != "" {
+		c.Cadence.Timezone = layer.Cadence.Timezone
+	}
+	if layer.Cadence.Anchor != "" {
+		c.Cadence.Anchor = layer.Cadence.Anchor
+	}
+	if len(layer.Replies) > 0 {
+		c.Replies = layer.Replies
+	}
+	if layer.Gitlab.URL != "" {
+		c.Gitlab.URL = layer.Gitlab.URL
+	}
+	if len(layer.Gitlab.Projects) > 0 {
+		c.Gitlab.Projects = layer.Gitlab.Projects
+	}
+	if layer.GithubURL != "" {
+		c.GithubURL = layer.GithubURL
+	}
+	for old, name := range layer.Renames {
+		if c.Renames == nil {
+			c.Renames = map[string]string{}
+		}
+		c.Renames[old] = name
+	}
+	if len(layer.KeepLabels) > 0 {
+		c.KeepLabels = layer.KeepLabels
+	}
+	if layer.NormalizeAliases != nil {
+		c.NormalizeAliases = layer.NormalizeAliases
+	}
+	for host, hostConfig := range layer.GithubHosts {
+		if c.GithubHosts == nil {
+			c.GithubHosts = map[string]GithubHost{}
+		}
+		c.GithubHosts[host] = hostConfig
+	}
+}
+
+// LoadConfig is the entrypoint into the config
+func LoadConfig(opts *Options) (*Config, error) {
+	var config Config
+
+	paths, err := configPaths(opts.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		logger.Debugln("Loading config:", path)
+		layer, err := readConfig(path)
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		config = Config{}
+		config.merge(layer)
+	}
+
+	// the flag wins over the config
+	if opts.GithubURL != "" {
+		config.GithubURL = opts.GithubURL
 	}
 
 	// set defaults
@@ -85,6 +510,8 @@ func LoadConfig(opts *Options) (*Config, error) {
 		config.Types = DefaultTypes
 	}
 
+	config.setDimensions()
+
 	if config.NextMilestone == "" {
 		config.NextMilestone = DefaultNextMilestone
 	}
@@ -93,5 +520,13 @@ func LoadConfig(opts *Options) (*Config, error) {
 		config.SomedayMilestone = DefaultSomedayMilestone
 	}
 
+	config.setMilestoneTiers()
+
+	for i := range config.Replies {
+		if config.Replies[i].Hotkey == "" {
+			config.Replies[i].Hotkey = strconv.Itoa(i + 1)
+		}
+	}
+
 	return &config, nil
 }
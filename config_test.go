@@ -0,0 +1,154 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// configTree is a temporary XDG_CONFIG_HOME and a repo with a .triage
+// directory at the top and a nested one further down
+func configTree(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "triage-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// inDir runs f in a directory with XDG_CONFIG_HOME set
+func inDir(t *testing.T, dir, xdg string, f func()) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", xdg)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	f()
+}
+
+func TestConfigPaths(t *testing.T) {
+	root := configTree(t, map[string]string{
+		"xdg/triage/config.yml":          "",
+		"repo/.triage/config.yml":        "",
+		"repo/sub/.triage/config.yml":    "",
+		"repo/sub/deeper/triage.yml":     "",
+		"repo/sub/deeper/elsewhere.yml":  "",
+		"repo/other/.triage/nothing.yml": "",
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		dir      string
+		explicit string
+		expected []string
+	}{
+		{"repo", "", []string{"xdg/triage/config.yml", "repo/.triage/config.yml"}},
+		// only the nearest .triage counts
+		{"repo/sub/deeper", "", []string{"xdg/triage/config.yml", "repo/sub/.triage/config.yml", "triage.yml"}},
+		{"repo/sub/deeper", "elsewhere.yml", []string{"xdg/triage/config.yml", "repo/sub/.triage/config.yml", "elsewhere.yml"}},
+		{"repo/other", "", []string{"xdg/triage/config.yml", "repo/.triage/config.yml"}},
+	}
+	for _, test := range tests {
+		inDir(t, filepath.Join(root, test.dir), filepath.Join(root, "xdg"), func() {
+			paths, err := configPaths(test.explicit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range paths {
+				paths[i] = strings.TrimPrefix(paths[i], root+string(filepath.Separator))
+			}
+			if strings.Join(paths, " ") != strings.Join(test.expected, " ") {
+				t.Errorf("%s %q: expected %v, got %v", test.dir, test.explicit, test.expected, paths)
+			}
+		})
+	}
+
+	// no user config, and a --config that isn't there
+	inDir(t, filepath.Join(root, "repo"), filepath.Join(root, "nowhere"), func() {
+		paths, err := configPaths("")
+		if err != nil || strings.Join(paths, " ") != filepath.Join(root, "repo/.triage/config.yml") {
+			t.Errorf("expected just the repo config, got %v %v", paths, err)
+		}
+		if _, err := configPaths("missing.yml"); err == nil {
+			t.Error("expected an error for a missing --config")
+		}
+	})
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	root := configTree(t, map[string]string{
+		"xdg/triage/config.yml": `
+projects: [wercker/foo, wercker/bar]
+normalize-aliases: true
+cadence:
+  length: 2w
+renames:
+  defect: bug
+`,
+		"repo/.triage/config.yml": `
+projects: [wercker/triage]
+normalize-aliases: false
+renames:
+  feature: enhancement
+`,
+		"repo/triage.yml": `
+next-milestone: Later
+cadence:
+  weekday: thursday
+`,
+	})
+	defer os.RemoveAll(root)
+
+	inDir(t, filepath.Join(root, "repo"), filepath.Join(root, "xdg"), func() {
+		config, err := LoadConfig(testOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(config.Projects, " ") != "wercker/triage" {
+			t.Errorf("expected the repo's projects, got %v", config.Projects)
+		}
+		if config.ShouldNormalizeAliases() {
+			t.Error("expected the repo to turn normalize-aliases back off")
+		}
+		if config.Cadence.Length != "2w" || config.Cadence.Weekday != "thursday" {
+			t.Errorf("expected the cadence to be merged, got %+v", config.Cadence)
+		}
+		if config.Renames["defect"] != "bug" || config.Renames["feature"] != "enhancement" {
+			t.Errorf("expected the renames to be merged, got %v", config.Renames)
+		}
+		if config.NextMilestone != "Later" || config.SomedayMilestone != DefaultSomedayMilestone {
+			t.Errorf("wrong milestones: %s %s", config.NextMilestone, config.SomedayMilestone)
+		}
+	})
+
+	// and left alone it stays on
+	inDir(t, root, filepath.Join(root, "xdg"), func() {
+		config, err := LoadConfig(testOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !config.ShouldNormalizeAliases() || strings.Join(config.Projects, " ") != "wercker/foo wercker/bar" {
+			t.Errorf("expected just the user config, got %+v", config)
+		}
+	})
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/nsf/termbox-go"
+)
+
+// Comment on an issue
+type Comment struct {
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// Comments on an issue, oldest first
+func (a *GithubAPI) Comments(issue *Issue) ([]*Comment, error) {
+	params := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	comments := []*Comment{}
+	for {
+		result, resp, err := a.client.Issues.ListComments(issue.Owner, issue.Repo, issue.Number, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result {
+			comment := &Comment{}
+			if c.User != nil && c.User.Login != nil {
+				comment.Author = *c.User.Login
+			}
+			if c.Body != nil {
+				comment.Body = *c.Body
+			}
+			if c.CreatedAt != nil {
+				comment.CreatedAt = *c.CreatedAt
+			}
+			comments = append(comments, comment)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		params.ListOptions.Page = resp.NextPage
+	}
+	return comments, nil
+}
+
+// DetailWindow shows everything about the current issue, full screen, and
+// scrolls through it
+type DetailWindow struct {
+	*ListWindow
+	shown  string
+	offset int
+	height int
+	// comments by issue url, fetched the first time the issue is shown
+	// and again if it's been updated since
+	comments    map[string][]*Comment
+	commentErrs map[string]error
+	fetching    map[string]bool
+	fetchedFor  map[string]time.Time
+}
+
+// NewDetailWindow ctor
+func NewDetailWindow(w *ListWindow) *DetailWindow {
+	return &DetailWindow{
+		ListWindow:  w,
+		comments:    map[string][]*Comment{},
+		commentErrs: map[string]error{},
+		fetching:    map[string]bool{},
+		fetchedFor:  map[string]time.Time{},
+	}
+}
+
+// Init noop (needed to prevent IssueList.Init being called)
+func (w *DetailWindow) Init() error {
+	return nil
+}
+
+// issue we're showing, the current one in the list
+func (w *DetailWindow) issue() *Issue {
+	if len(w.currentIssues) == 0 {
+		return nil
+	}
+	return w.currentIssues[w.currentIndex]
+}
+
+// fetchComments in the background and redraw when we have them
+func (w *DetailWindow) fetchComments(issue *Issue) {
+	defer w.loading.Done()
+	comments, err := w.API.Comments(issue)
+	if err != nil {
+		logger.Errorln("Couldn't fetch comments for", issue.URL, err)
+	}
+
+	w.drawSync.Lock()
+	delete(w.fetching, issue.URL)
+	if err != nil {
+		w.commentErrs[issue.URL] = err
+	} else {
+		w.comments[issue.URL] = comments
+	}
+	w.drawSync.Unlock()
+	w.Redraw()
+}
+
+// Draw the issue, its body and the comments
+func (w *DetailWindow) Draw(x, y, x1, y1 int) {
+	issue := w.issue()
+	if issue == nil {
+		printLine("nothing to show [esc] back", x+2, y)
+		return
+	}
+	if issue.URL != w.shown {
+		w.shown = issue.URL
+		w.offset = 0
+	}
+	updated, fetched := w.fetchedFor[issue.URL]
+	if (!fetched || issue.UpdatedAt.After(updated)) && !w.fetching[issue.URL] {
+		delete(w.commentErrs, issue.URL)
+		w.fetchedFor[issue.URL] = issue.UpdatedAt
+		w.fetching[issue.URL] = true
+		w.loading.Add(1)
+		go w.fetchComments(issue)
+	}
+
+	printLine(fmt.Sprintf("[esc] back [up/down] scroll [pgup/pgdn] page [a] assign [c] comment%s", w.repliesMenu()), x+2, y)
+
+	lines := w.render(issue, x1-x-3)
+	w.height = y1 - y
+	if bottom := len(lines) - w.height; w.offset > bottom {
+		w.offset = bottom
+	}
+	if w.offset < 0 {
+		w.offset = 0
+	}
+
+	if w.offset > 0 {
+		screen.SetCell(x, y+1, '↑', termbox.ColorDefault, termbox.ColorDefault)
+	}
+	for i, line := range lines[w.offset:] {
+		if i >= w.height {
+			screen.SetCell(x, y1, '↓', termbox.ColorDefault, termbox.ColorDefault)
+			break
+		}
+		printRunes(line.Text, x+2, y+1+i, x1, line.Fg)
+	}
+
+	last := w.offset + w.height
+	if last > len(lines) {
+		last = len(lines)
+	}
+	w.Status += fmt.Sprintf("lines %d-%d of %d ", w.offset+1, last, len(lines))
+}
+
+// render everything we show about an issue into lines width wide
+func (w *DetailWindow) render(issue *Issue, width int) []styledLine {
+	bold := termbox.ColorDefault | termbox.AttrBold
+	dim := termbox.Attribute(245)
+
+	lines := []styledLine{}
+	add := func(fg termbox.Attribute, text string) {
+		for _, l := range wordWrap(text, width) {
+			lines = append(lines, styledLine{l, fg})
+		}
+	}
+
+	add(bold, fmt.Sprintf("%s/%s#%d %s", issue.Owner, issue.Repo, issue.Number, issue.Title))
+	opened := "opened"
+	if issue.Author != "" {
+		opened += " by " + issue.Author
+	}
+	if !issue.CreatedAt.IsZero() {
+		opened += " on " + formatTime(issue.CreatedAt)
+	}
+	if !issue.UpdatedAt.IsZero() {
+		opened += ", updated " + formatTime(issue.UpdatedAt)
+	}
+	add(dim, opened)
+
+	assignees := "nobody"
+	if len(issue.Assignees) > 0 {
+		assignees = strings.Join(issue.Assignees, ", ")
+	}
+	add(termbox.ColorDefault, "assigned to "+assignees)
+	milestone := "none"
+	if issue.Milestone != nil && issue.Milestone.Milestone != nil {
+		milestone = issue.Milestone.Title
+	}
+	add(termbox.ColorDefault, "milestone: "+milestone)
+	labels := "none"
+	if len(issue.Labels) > 0 {
+		labels = strings.Join(issue.Labels, ", ")
+	}
+	add(termbox.ColorDefault, "labels: "+labels)
+	add(dim, issue.URL)
+	lines = append(lines, styledLine{})
+
+	if strings.TrimSpace(issue.Body) == "" {
+		add(dim, "(no description)")
+	} else {
+		lines = append(lines, renderMarkdown(issue.Body, width)...)
+	}
+	lines = append(lines, styledLine{})
+
+	comments, fetched := w.comments[issue.URL]
+	switch {
+	case w.commentErrs[issue.URL] != nil:
+		add(dim, fmt.Sprintf("-- couldn't fetch comments: %s", w.commentErrs[issue.URL]))
+	case !fetched:
+		add(dim, "-- loading comments...")
+	case len(comments) == 0:
+		add(dim, "-- no comments")
+	case len(comments) == 1:
+		add(dim, "-- 1 comment")
+	default:
+		add(dim, fmt.Sprintf("-- %d comments", len(comments)))
+	}
+	for _, c := range comments {
+		lines = append(lines, styledLine{})
+		header := c.Author
+		if !c.CreatedAt.IsZero() {
+			header += " on " + formatTime(c.CreatedAt)
+		}
+		add(bold, header)
+		for _, l := range renderMarkdown(c.Body, width-2) {
+			l.Text = "  " + l.Text
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// formatTime in local time, to the minute
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// HandleEvent scrolls, anything that backs out goes back to the list
+func (w *DetailWindow) HandleEvent(ev termbox.Event) (bool, error) {
+	switch ev.Type {
+	case termbox.EventKey:
+		switch ev.Key {
+		case termbox.KeyEsc, termbox.KeyArrowLeft, termbox.KeyEnter:
+			w.Focus = w.List
+			w.ContextMenu = w.ListMenu
+			return true, nil
+		case termbox.KeyArrowUp:
+			w.offset--
+			return true, nil
+		case termbox.KeyArrowDown:
+			w.offset++
+			return true, nil
+		case termbox.KeyPgup:
+			w.offset -= w.page()
+			return true, nil
+		case termbox.KeyPgdn, termbox.KeySpace:
+			w.offset += w.page()
+			return true, nil
+		default:
+			switch ev.Ch {
+			case 'q':
+				w.Focus = w.List
+				w.ContextMenu = w.ListMenu
+				return true, nil
+			case 'k':
+				w.offset--
+				return true, nil
+			case 'j':
+				w.offset++
+				return true, nil
+			case 'g':
+				w.offset = 0
+				return true, nil
+			case 'G':
+				// Draw keeps it on the screen
+				w.offset = 1 << 30
+				return true, nil
+			case 'a':
+				if issue := w.issue(); issue != nil {
+					w.assign([]*Issue{issue})
+				}
+				return true, nil
+			case 'c':
+				if issue := w.issue(); issue != nil {
+					w.compose([]*Issue{issue}, "")
+				}
+				return true, nil
+			}
+			// a stray key while reading shouldn't post anything
+			if issue := w.issue(); issue != nil && w.replyEvent(ev, []*Issue{issue}, true) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// page is how far pgup and pgdn go, leaving a line of context
+func (w *DetailWindow) page() int {
+	if w.height > 2 {
+		return w.height - 1
+	}
+	return 1
+}
+
+// styledLine is a line of text and the color to draw it in
+type styledLine struct {
+	Text string
+	Fg   termbox.Attribute
+}
+
+// printRunes draws a line that might not be ascii, cut off at x1
+func printRunes(str string, x, y, x1 int, fg termbox.Attribute) {
+	for i, c := range []rune(str) {
+		if x+i >= x1 {
+			return
+		}
+		screen.SetCell(x+i, y, c, fg, termbox.ColorDefault)
+	}
+}
+
+var (
+	headingRegexp = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+	ruleRegexp    = regexp.MustCompile(`^\s*([-*_]\s*){3,}$`)
+	quoteRegexp   = regexp.MustCompile(`^>\s?`)
+	itemRegexp    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+`)
+	imageRegexp   = regexp.MustCompile(`!\[([^\]]*)\]\(([^)]*)\)`)
+	linkRegexp    = regexp.MustCompile(`\[([^\]]+)\]\(([^)]*)\)`)
+	strongRegexp  = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	codeRegexp    = regexp.MustCompile("`([^`]+)`")
+)
+
+// renderMarkdown does just enough markdown to make an issue readable in a
+// terminal: headings, code blocks, quotes, lists and links, wrapped to
+// width
+func renderMarkdown(text string, width int) []styledLine {
+	if width < 10 {
+		width = 10
+	}
+	heading := termbox.ColorDefault | termbox.AttrBold | termbox.AttrUnderline
+	code := termbox.ColorYellow
+	quote := termbox.Attribute(245)
+
+	lines := []styledLine{}
+	add := func(fg termbox.Attribute, first, rest, text string) {
+		for i, l := range wordWrap(text, width-len(first)) {
+			prefix := first
+			if i > 0 {
+				prefix = rest
+			}
+			lines = append(lines, styledLine{prefix + l, fg})
+		}
+	}
+
+	fenced := false
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Replace(line, "\t", "    ", -1)
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		// code is shown as-is, it gets cut off rather than wrapped
+		if fenced {
+			lines = append(lines, styledLine{"  " + line, code})
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			lines = append(lines, styledLine{})
+		case headingRegexp.MatchString(line):
+			add(heading, "", "", inlineMarkdown(headingRegexp.FindStringSubmatch(line)[1]))
+		case ruleRegexp.MatchString(line):
+			lines = append(lines, styledLine{strings.Repeat("-", width), quote})
+		case quoteRegexp.MatchString(line):
+			add(quote, "| ", "| ", inlineMarkdown(quoteRegexp.ReplaceAllString(line, "")))
+		case itemRegexp.MatchString(line):
+			m := itemRegexp.FindStringSubmatch(line)
+			marker := m[2]
+			if marker == "*" || marker == "+" {
+				marker = "-"
+			}
+			first := m[1] + marker + " "
+			add(termbox.ColorDefault, first, strings.Repeat(" ", len(first)), inlineMarkdown(line[len(m[0]):]))
+		case strings.HasPrefix(line, "    "):
+			lines = append(lines, styledLine{"  " + line[4:], code})
+		default:
+			add(termbox.ColorDefault, "", "", inlineMarkdown(line))
+		}
+	}
+	return lines
+}
+
+// inlineMarkdown takes out the markup we can't show, links keep their url
+func inlineMarkdown(text string) string {
+	text = imageRegexp.ReplaceAllString(text, "[image: $1]")
+	text = linkRegexp.ReplaceAllString(text, "$1 <$2>")
+	text = strongRegexp.ReplaceAllString(text, "$2")
+	text = codeRegexp.ReplaceAllString(text, "$1")
+	return strings.TrimSpace(text)
+}
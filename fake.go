@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// FakeAPI is an in-memory issue tracker, seeded from the raw_issues.json
+// that `--debug` dumps, it remembers every mutation made against it
+type FakeAPI struct {
+	Issues []github.Issue
+	Calls  []string
+	// Threads are the comments on each issue by url
+	Threads    map[string][]*Comment
+	milestones map[string][]*Milestone
+}
+
+// NewFakeAPI constructor
+func NewFakeAPI(issues []github.Issue, milestones map[string][]*Milestone) *FakeAPI {
+	return &FakeAPI{Issues: issues, Threads: map[string][]*Comment{}, milestones: milestones}
+}
+
+// LoadFakeAPI reads a raw_issues.json style fixture, the milestones are
+// worked out from the ones the issues are in
+func LoadFakeAPI(path string) (*FakeAPI, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := []*Issue{}
+	err = json.Unmarshal(data, &fixture)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []github.Issue{}
+	milestones := map[string][]*Milestone{}
+	for _, fix := range fixture {
+		issue := github.Issue{
+			Number:  github.Int(fix.Number),
+			Title:   github.String(fix.Title),
+			Body:    github.String(fix.Body),
+			HTMLURL: github.String(fix.URL),
+			State:   github.String("open"),
+		}
+		for _, label := range fix.Labels {
+			issue.Labels = append(issue.Labels, github.Label{Name: github.String(label)})
+		}
+		if fix.Author != "" {
+			issue.User = &github.User{Login: github.String(fix.Author)}
+		}
+		for _, login := range fix.Assignees {
+			issue.Assignees = append(issue.Assignees, &github.User{Login: github.String(login)})
+		}
+
+		if fix.Milestone != nil && fix.Milestone.Milestone != nil {
+			m := fix.Milestone.Milestone
+			issue.Milestone = &github.Milestone{
+				Number: github.Int(m.Number),
+				Title:  github.String(m.Title),
+				DueOn:  m.DueOn,
+			}
+			// the index is close enough to the tier for a fixture
+			if i := fix.Milestone.Index; i > 0 {
+				for len(milestones[fix.Project]) < i {
+					milestones[fix.Project] = append(milestones[fix.Project], nil)
+				}
+				milestones[fix.Project][i-1] = m
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return NewFakeAPI(issues, milestones), nil
+}
+
+// find the stored issue for one of our Issues and mark it as updated
+func (a *FakeAPI) find(issue *Issue) (*github.Issue, error) {
+	for i := range a.Issues {
+		if *a.Issues[i].HTMLURL == issue.URL {
+			now := time.Now()
+			a.Issues[i].UpdatedAt = &now
+			return &a.Issues[i], nil
+		}
+	}
+	return nil, fmt.Errorf("No such issue: %s#%d", issue.Project, issue.Number)
+}
+
+// inProject is whether the issue belongs to an owner/repo project
+func inProject(issue github.Issue, project string) bool {
+	owner, repo, _ := ownerRepoFromURL(*issue.HTMLURL)
+	return fmt.Sprintf("%s/%s", owner, repo) == project
+}
+
+// record a call for later inspection
+func (a *FakeAPI) record(format string, args ...interface{}) {
+	a.Calls = append(a.Calls, fmt.Sprintf(format, args...))
+}
+
+// results sends the issues matching filter as a single page, since works
+// like the real thing except that issues without an update time are
+// always included
+func (a *FakeAPI) results(since time.Time, filter func(github.Issue) bool) <-chan *IssueResult {
+	issues := []github.Issue{}
+	for _, issue := range a.Issues {
+		if since.IsZero() && issue.State != nil && *issue.State != "open" {
+			continue
+		}
+		if !since.IsZero() && issue.UpdatedAt != nil && issue.UpdatedAt.Before(since) {
+			continue
+		}
+		if filter(issue) {
+			issues = append(issues, issue)
+		}
+	}
+	out := make(chan *IssueResult, 1)
+	out <- &IssueResult{issues, nil}
+	close(out)
+	return out
+}
+
+// Milestones we worked out from the fixture
+func (a *FakeAPI) Milestones(project string) ([]*Milestone, error) {
+	ms := a.milestones[project]
+	if ms == nil {
+		return nil, fmt.Errorf("Did not find valid milestones for: %s", project)
+	}
+	return ms, nil
+}
+
+// OpenMilestones are the tiers plus any milestone an open issue is in
+func (a *FakeAPI) OpenMilestones(project string) ([]*Milestone, error) {
+	seen := map[int]bool{}
+	milestones := []*Milestone{}
+	for _, m := range a.milestones[project] {
+		if m != nil && !seen[m.Number] {
+			seen[m.Number] = true
+			milestones = append(milestones, m)
+		}
+	}
+	for _, issue := range a.Issues {
+		if !inProject(issue, project) || issue.Milestone == nil || (issue.State != nil && *issue.State != "open") {
+			continue
+		}
+		if m := issue.Milestone; !seen[*m.Number] {
+			seen[*m.Number] = true
+			milestones = append(milestones, &Milestone{Number: *m.Number, Title: *m.Title, DueOn: m.DueOn})
+		}
+	}
+	return milestones, nil
+}
+
+// MilestoneIssues are the open issues in the milestone
+func (a *FakeAPI) MilestoneIssues(project string, milestone *Milestone) ([]github.Issue, error) {
+	issues := []github.Issue{}
+	for _, issue := range a.Issues {
+		if !inProject(issue, project) || issue.Milestone == nil || (issue.State != nil && *issue.State != "open") {
+			continue
+		}
+		if *issue.Milestone.Number == milestone.Number {
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// Search only pays attention to repo: in the query
+func (a *FakeAPI) Search(query string, since time.Time) <-chan *IssueResult {
+	repos := []string{}
+	for _, part := range strings.Fields(query) {
+		if strings.HasPrefix(part, "repo:") {
+			repos = append(repos, strings.TrimPrefix(part, "repo:"))
+		}
+	}
+	return a.results(since, func(issue github.Issue) bool {
+		if len(repos) == 0 {
+			return true
+		}
+		owner, repo, _ := ownerRepoFromURL(*issue.HTMLURL)
+		for _, r := range repos {
+			if r == fmt.Sprintf("%s/%s", owner, repo) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ByOrg returns the issues with a matching owner
+func (a *FakeAPI) ByOrg(org string, since time.Time) <-chan *IssueResult {
+	return a.results(since, func(issue github.Issue) bool {
+		owner, _, _ := ownerRepoFromURL(*issue.HTMLURL)
+		return owner == org
+	})
+}
+
+// ByUser returns everything
+func (a *FakeAPI) ByUser(since time.Time) <-chan *IssueResult {
+	return a.results(since, func(issue github.Issue) bool { return true })
+}
+
+// Get a copy of the stored issue
+func (a *FakeAPI) Get(issue *Issue) (*github.Issue, error) {
+	for _, stored := range a.Issues {
+		if *stored.HTMLURL == issue.URL {
+			return &stored, nil
+		}
+	}
+	return nil, fmt.Errorf("No such issue: %s#%d", issue.Project, issue.Number)
+}
+
+// Collaborators are everybody who opened or is assigned to an issue in
+// the project
+func (a *FakeAPI) Collaborators(project string) ([]string, error) {
+	seen := map[string]bool{}
+	logins := []string{}
+	add := func(user *github.User) {
+		if user != nil && user.Login != nil && !seen[*user.Login] {
+			seen[*user.Login] = true
+			logins = append(logins, *user.Login)
+		}
+	}
+	for _, issue := range a.Issues {
+		if !inProject(issue, project) {
+			continue
+		}
+		add(issue.User)
+		for _, user := range issue.Assignees {
+			add(user)
+		}
+	}
+	sort.Strings(logins)
+	return logins, nil
+}
+
+// Comments on the stored issue
+func (a *FakeAPI) Comments(issue *Issue) ([]*Comment, error) {
+	if _, err := a.find(issue); err != nil {
+		return nil, err
+	}
+	return a.Threads[issue.URL], nil
+}
+
+// SetMilestone on the stored issue
+func (a *FakeAPI) SetMilestone(issue *Issue, milestone *Milestone) error {
+	stored, err := a.find(issue)
+	if err != nil {
+		return err
+	}
+	if milestone == nil {
+		a.record("SetMilestone %s#%d none", issue.Project, issue.Number)
+		stored.Milestone = nil
+		return nil
+	}
+	a.record("SetMilestone %s#%d %d", issue.Project, issue.Number, milestone.Number)
+	stored.Milestone = &github.Milestone{
+		Number: github.Int(milestone.Number),
+		Title:  github.String(milestone.Title),
+		DueOn:  milestone.DueOn,
+	}
+	return nil
+}
+
+// ReplaceLabels on the stored issue
+func (a *FakeAPI) ReplaceLabels(issue *Issue, labels []string) error {
+	stored, err := a.find(issue)
+	if err != nil {
+		return err
+	}
+	a.record("ReplaceLabels %s#%d %s", issue.Project, issue.Number, strings.Join(labels, ","))
+	stored.Labels = nil
+	for _, label := range labels {
+		stored.Labels = append(stored.Labels, github.Label{Name: github.String(label)})
+	}
+	return nil
+}
+
+// AddLabel on the stored issue
+func (a *FakeAPI) AddLabel(issue *Issue, label string) error {
+	stored, err := a.find(issue)
+	if err != nil {
+		return err
+	}
+	a.record("AddLabel %s#%d %s", issue.Project, issue.Number, label)
+	stored.Labels = append(stored.Labels, github.Label{Name: github.String(label)})
+	return nil
+}
+
+// RemoveLabel on the stored issue
+func (a *FakeAPI) RemoveLabel(issue *Issue, label string) error {
+	stored, err := a.find(issue)
+	if err != nil {
+		return err
+	}
+	a.record("RemoveLabel %s#%d %s", issue.Project, issue.Number, label)
+	labels := []github.Label{}
+	for _, l := range stored.Labels {
+		if *l.Name != label {
+			labels = append(labels, l)
+		}
+	}
+	stored.Labels = labels
+	return nil
+}
+
+// SetState on the stored issue
+func (a *FakeAPI) SetState(issue *Issue, state string) error {
+	stored, err := a.find(issue)
+	if err != nil {
+		return err
+	}
+	a.record("SetState %s#%d %s", issue.Project, issue.Number, state)
+	stored.State = github.String(state)
+	return nil
+}
+
+// AddComment to the stored issue's thread
+func (a *FakeAPI) AddComment(issue *Issue, body string) error {
+	if _, err := a.find(issue); err != nil {
+		return err
+	}
+	a.record("AddComment %s#%d %s", issue.Project, issue.Number, strings.Replace(body, "\n", "\\n", -1))
+	a.Threads[issue.URL] = append(a.Threads[issue.URL], &Comment{Author: "you", Body: body, CreatedAt: time.Now()})
+	return nil
+}
+
+// SetAssignees on the stored issue
+func (a *FakeAPI) SetAssignees(issue *Issue, logins []string) error {
+	stored, err := a.find(issue)
+	if err != nil {
+		return err
+	}
+	a.record("SetAssignees %s#%d %s", issue.Project, issue.Number, strings.Join(logins, ","))
+	stored.Assignees = nil
+	for _, login := range logins {
+		stored.Assignees = append(stored.Assignees, &github.User{Login: github.String(login)})
+	}
+	return nil
+}
+
+// CloseMilestone is only recorded, closed milestones aren't tracked
+func (a *FakeAPI) CloseMilestone(project string, milestone *Milestone) error {
+	a.record("CloseMilestone %s %d", project, milestone.Number)
+	return nil
+}
@@ -1,13 +1,134 @@
 package main
 
-import "strings"
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// splitProject separates the host from a host-qualified project like
+// ghe.corp/team/repo, the host is empty for a plain owner/repo
+func splitProject(s string) (string, string) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 2 && strings.Contains(parts[0], ".") && strings.Contains(parts[1], "/") {
+		return parts[0], parts[1]
+	}
+	return "", s
+}
 
 func ownerRepo(s string) (string, string, error) {
+	_, s = splitProject(s)
 	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("Expected a project like owner/repo or host/owner/repo, got: %s", s)
+	}
 	return parts[0], parts[1], nil
 }
 
-func ownerRepoFromURL(url string) (string, string, error) {
-	parts := strings.Split(url, "/")
-	return parts[len(parts)-4], parts[len(parts)-3], nil
+// ownerRepoFromURL pulls the owner and repo out of an issue's url, GitLab
+// urls may have nested groups and a "-" before "issues", and GitHub
+// Enterprise api urls have /api/v3/repos in front
+func ownerRepoFromURL(s string) (string, string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", "", err
+	}
+	path := strings.Trim(u.Path, "/")
+	path = strings.TrimPrefix(path, "api/v3/")
+	path = strings.TrimPrefix(path, "repos/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 4 {
+		return "", "", fmt.Errorf("Not an issue url: %s", s)
+	}
+	// drop the issues/<number>
+	parts = parts[:len(parts)-2]
+	if parts[len(parts)-1] == "-" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts[:len(parts)-1], "/"), parts[len(parts)-1], nil
+}
+
+// hostFromURL is the host part of a url, api.github.com is github.com
+func hostFromURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	if u.Host == "api.github.com" {
+		return "github.com"
+	}
+	return u.Host
+}
+
+// GithubClients hands out a client per github host, the empty host being
+// whichever github we use by default
+type GithubClients struct {
+	opts    *Options
+	config  *Config
+	clients map[string]*github.Client
+}
+
+// NewGithubClients constructor
+func NewGithubClients(opts *Options, config *Config) *GithubClients {
+	return &GithubClients{opts, config, map[string]*github.Client{}}
+}
+
+// Host gets the client for a host, pointed at the right api urls and
+// with the right token
+func (c *GithubClients) Host(host string) (*github.Client, error) {
+	if host == c.config.GithubHost() {
+		host = ""
+	}
+	if client, ok := c.clients[host]; ok {
+		return client, nil
+	}
+
+	apiURL := c.config.GithubURL
+	token := c.opts.APIToken
+	if host != "" {
+		hostConfig := c.config.GithubHosts[host]
+		apiURL = hostConfig.URL
+		if apiURL == "" {
+			apiURL = fmt.Sprintf("https://%s/api/v3/", host)
+		}
+		if hostConfig.TokenEnv != "" {
+			token = os.Getenv(hostConfig.TokenEnv)
+		}
+	}
+
+	client := github.NewClient(TokenClient(token))
+	if apiURL != "" {
+		if !strings.HasSuffix(apiURL, "/") {
+			apiURL += "/"
+		}
+		base, err := url.Parse(apiURL)
+		if err != nil {
+			return nil, err
+		}
+		upload, err := url.Parse(strings.Replace(apiURL, "/api/v3/", "/api/uploads/", 1))
+		if err != nil {
+			return nil, err
+		}
+		client.BaseURL = base
+		client.UploadURL = upload
+	}
+	c.clients[host] = client
+	return client, nil
+}
+
+// For a project, along with the owner and repo to use with the client
+func (c *GithubClients) For(project string) (*github.Client, string, string, error) {
+	owner, repo, err := ownerRepo(project)
+	if err != nil {
+		return nil, "", "", err
+	}
+	host, _ := splitProject(project)
+	client, err := c.Host(host)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return client, owner, repo, nil
 }
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestSplitProject(t *testing.T) {
+	tests := []struct {
+		project string
+		host    string
+		rest    string
+	}{
+		{"wercker/triage", "", "wercker/triage"},
+		{"github.com/wercker/triage", "github.com", "wercker/triage"},
+		{"ghe.corp/team/repo", "ghe.corp", "team/repo"},
+		{"wercker.io/triage", "", "wercker.io/triage"},
+		{"ghe.corp/wercker.io/triage", "ghe.corp", "wercker.io/triage"},
+		{"group/sub/repo", "", "group/sub/repo"},
+		{"ghe.corp/team/", "ghe.corp", "team/"},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		host, rest := splitProject(test.project)
+		if host != test.host || rest != test.rest {
+			t.Errorf("%q: got %q %q, expected %q %q", test.project, host, rest, test.host, test.rest)
+		}
+	}
+}
+
+func TestOwnerRepoFromURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		owner string
+		repo  string
+	}{
+		{"https://github.com/wercker/triage/issues/12", "wercker", "triage"},
+		{"https://github.com/wercker.io/triage/issues/12", "wercker.io", "triage"},
+		{"https://api.github.com/repos/wercker/triage/issues/12", "wercker", "triage"},
+		{"https://ghe.corp/api/v3/repos/team/repo/issues/1", "team", "repo"},
+		{"https://ghe.corp/team/repo/issues/1", "team", "repo"},
+		{"https://gitlab.com/group/repo/issues/3", "group", "repo"},
+		{"https://gitlab.com/group/sub/repo/-/issues/3", "group/sub", "repo"},
+		{"https://gitlab.corp/a.b/c/d/repo/-/issues/3/", "a.b/c/d", "repo"},
+	}
+	for _, test := range tests {
+		owner, repo, err := ownerRepoFromURL(test.url)
+		if err != nil {
+			t.Errorf("%s: %s", test.url, err)
+			continue
+		}
+		if owner != test.owner || repo != test.repo {
+			t.Errorf("%s: got %s %s, expected %s %s", test.url, owner, repo, test.owner, test.repo)
+		}
+	}
+
+	for _, bad := range []string{"https://github.com/wercker/triage", "https://github.com/", "%zz"} {
+		if _, _, err := ownerRepoFromURL(bad); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
+
+func TestHostFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/wercker/triage/issues/12":          "github.com",
+		"https://api.github.com/repos/wercker/triage/issues/1": "github.com",
+		"https://ghe.corp/api/v3/":                             "ghe.corp",
+		"https://ghe.corp:8443/team/repo/issues/1":             "ghe.corp:8443",
+		"https://gitlab.com/group/sub/repo/-/issues/3":         "gitlab.com",
+		"":    "",
+		"%zz": "",
+	}
+	for url, expected := range tests {
+		if host := hostFromURL(url); host != expected {
+			t.Errorf("%q: got %q, expected %q", url, host, expected)
+		}
+	}
+}
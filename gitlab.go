@@ -0,0 +1,494 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// GitlabAPI is the implementation of the issue tracker interface for GitLab,
+// issues are translated into github.Issues so everything downstream of the
+// API can stay the same
+type GitlabAPI struct {
+	client *http.Client
+	opts   *Options
+	config *Config
+}
+
+// NewGitlabAPI constructor
+func NewGitlabAPI(client *http.Client, opts *Options, config *Config) *GitlabAPI {
+	return &GitlabAPI{client, opts, config}
+}
+
+// gitlabIssue is the bit of a GitLab issue we care about
+type gitlabIssue struct {
+	IID         int              `json:"iid"`
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	State       string           `json:"state"`
+	Labels      []string         `json:"labels"`
+	Milestone   *gitlabMilestone `json:"milestone"`
+	WebURL      string           `json:"web_url"`
+	CreatedAt   *time.Time       `json:"created_at"`
+	UpdatedAt   *time.Time       `json:"updated_at"`
+	Author      *gitlabUser      `json:"author"`
+	Assignees   []gitlabUser     `json:"assignees"`
+}
+
+// gitlabUser is who wrote or is assigned to something
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+// gitlabNote is a comment on an issue, or something GitLab noted happened
+// to it if System is set
+type gitlabNote struct {
+	Body      string     `json:"body"`
+	Author    gitlabUser `json:"author"`
+	CreatedAt *time.Time `json:"created_at"`
+	System    bool       `json:"system"`
+}
+
+// gitlabMilestone is a project or group milestone
+type gitlabMilestone struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	DueDate string `json:"due_date"`
+}
+
+// dueOn parses the due date, GitLab only gives us a day
+func (m *gitlabMilestone) dueOn() *time.Time {
+	if m.DueDate == "" {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", m.DueDate)
+	if err != nil {
+		logger.Warnln("Bad due date for milestone:", m.Title, m.DueDate)
+		return nil
+	}
+	return &t
+}
+
+// milestone translates a GitLab milestone into one of ours
+func (m *gitlabMilestone) milestone() *Milestone {
+	return &Milestone{Number: m.ID, Title: m.Title, DueOn: m.dueOn()}
+}
+
+// githubIssue translates a GitLab issue into the shape NewIssue expects
+func (i *gitlabIssue) githubIssue() github.Issue {
+	// GitLab calls it "opened"
+	state := "open"
+	if i.State != "opened" {
+		state = i.State
+	}
+	issue := github.Issue{
+		Number:    &i.IID,
+		Title:     &i.Title,
+		Body:      &i.Description,
+		State:     &state,
+		HTMLURL:   &i.WebURL,
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+	}
+	for idx := range i.Labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: &i.Labels[idx]})
+	}
+	if i.Author != nil {
+		issue.User = &github.User{Login: &i.Author.Username}
+	}
+	for idx := range i.Assignees {
+		issue.Assignees = append(issue.Assignees, &github.User{Login: &i.Assignees[idx].Username})
+	}
+	if i.Milestone != nil {
+		issue.Milestone = &github.Milestone{
+			Number: &i.Milestone.ID,
+			Title:  &i.Milestone.Title,
+			DueOn:  i.Milestone.dueOn(),
+		}
+	}
+	return issue
+}
+
+// gitlabPath escapes a project or group path for use as an id in the api
+func gitlabPath(s string) string {
+	return url.QueryEscape(s)
+}
+
+// get does an authenticated GET against the GitLab api and decodes the
+// response into v, returning the next page number (0 if there isn't one)
+func (a *GitlabAPI) get(path string, params url.Values, v interface{}) (int, error) {
+	return a.do("GET", path, params, v)
+}
+
+// put does an authenticated PUT against the GitLab api, v may be nil if we
+// don't care about the response
+func (a *GitlabAPI) put(path string, params url.Values, v interface{}) error {
+	_, err := a.do("PUT", path, params, v)
+	return err
+}
+
+// do sends the request with params in the query string, which GitLab
+// accepts for writes as well as reads
+func (a *GitlabAPI) do(method, path string, params url.Values, v interface{}) (int, error) {
+	u := fmt.Sprintf("%s/api/v4/%s", strings.TrimRight(a.config.Gitlab.URL, "/"), path)
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	logger.Debugln("GitLab", method, u)
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", a.opts.GitlabToken)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("GitLab: %s %s: %s", method, path, resp.Status)
+	}
+
+	if v != nil {
+		err = json.NewDecoder(resp.Body).Decode(v)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	next, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+	return next, nil
+}
+
+// listIssues pages through an issues endpoint
+func (a *GitlabAPI) listIssues(path string, params url.Values, out chan<- *IssueResult) error {
+	params.Set("per_page", "100")
+	page := 1
+	for page != 0 {
+		params.Set("page", strconv.Itoa(page))
+		issues := []gitlabIssue{}
+		next, err := a.get(path, params, &issues)
+		if err != nil {
+			return err
+		}
+		result := []github.Issue{}
+		for i := range issues {
+			result = append(result, issues[i].githubIssue())
+		}
+		out <- &IssueResult{result, nil}
+		page = next
+	}
+	return nil
+}
+
+// Milestones for a GitLab project, including the milestones of its group
+func (a *GitlabAPI) Milestones(project string) ([]*Milestone, error) {
+	defer profile("GitlabAPI.Milestones").Stop()
+	logger.Debugln("Fetching milestones for:", project)
+
+	params := url.Values{"state": {"active"}}
+	milestones, err := a.listMilestones(fmt.Sprintf("projects/%s/milestones", gitlabPath(project)), params)
+	if err != nil {
+		return nil, err
+	}
+
+	// user namespaces aren't groups, so a failure here just means there
+	// are no group milestones to look at
+	if i := strings.LastIndex(project, "/"); i > 0 {
+		group, err := a.listMilestones(fmt.Sprintf("groups/%s/milestones", gitlabPath(project[:i])), params)
+		if err != nil {
+			logger.Debugln("No group milestones for:", project, err)
+		}
+		milestones = append(milestones, group...)
+	}
+
+	ours := []*Milestone{}
+	for i := range milestones {
+		ours = append(ours, milestones[i].milestone())
+	}
+	return triageMilestones(project, ours, a.config)
+}
+
+// listMilestones pages through a milestones endpoint
+func (a *GitlabAPI) listMilestones(path string, params url.Values) ([]gitlabMilestone, error) {
+	params.Set("per_page", "100")
+	milestones := []gitlabMilestone{}
+	page := 1
+	for page != 0 {
+		params.Set("page", strconv.Itoa(page))
+		batch := []gitlabMilestone{}
+		next, err := a.get(path, params, &batch)
+		if err != nil {
+			return nil, err
+		}
+		milestones = append(milestones, batch...)
+		page = next
+	}
+	return milestones, nil
+}
+
+// OpenMilestones of the project itself, group milestones are shared with
+// the rest of the group so they aren't ours to roll over or close
+func (a *GitlabAPI) OpenMilestones(project string) ([]*Milestone, error) {
+	logger.Debugln("Fetching milestones for:", project)
+	milestones, err := a.listMilestones(fmt.Sprintf("projects/%s/milestones", gitlabPath(project)), url.Values{"state": {"active"}})
+	if err != nil {
+		return nil, err
+	}
+	ours := []*Milestone{}
+	for i := range milestones {
+		ours = append(ours, milestones[i].milestone())
+	}
+	return ours, nil
+}
+
+// MilestoneIssues are all the open issues in a milestone
+func (a *GitlabAPI) MilestoneIssues(project string, milestone *Milestone) ([]github.Issue, error) {
+	path := fmt.Sprintf("projects/%s/issues", gitlabPath(project))
+	params := url.Values{"milestone": {milestone.Title}, "state": {"opened"}, "per_page": {"100"}}
+	result := []github.Issue{}
+	page := 1
+	for page != 0 {
+		params.Set("page", strconv.Itoa(page))
+		issues := []gitlabIssue{}
+		next, err := a.get(path, params, &issues)
+		if err != nil {
+			return nil, err
+		}
+		for i := range issues {
+			result = append(result, issues[i].githubIssue())
+		}
+		page = next
+	}
+	return result, nil
+}
+
+// CloseMilestone marks a project milestone closed
+func (a *GitlabAPI) CloseMilestone(project string, milestone *Milestone) error {
+	path := fmt.Sprintf("projects/%s/milestones/%d", gitlabPath(project), milestone.Number)
+	return a.put(path, url.Values{"state_event": {"close"}}, nil)
+}
+
+// sinceParams adds updated_after, if since is set we want closed issues
+// too so any state we've set is removed
+func sinceParams(params url.Values, since time.Time) url.Values {
+	if !since.IsZero() {
+		params.Del("state")
+		params.Set("updated_after", since.UTC().Format(time.RFC3339))
+	}
+	return params
+}
+
+// Search understands the subset of the github search syntax that triage
+// itself generates: is:open, is:issue and repo:, anything else is passed
+// along as a text search
+func (a *GitlabAPI) Search(query string, since time.Time) <-chan *IssueResult {
+	params := url.Values{}
+	repos := []string{}
+	terms := []string{}
+	for _, part := range strings.Fields(query) {
+		switch {
+		case part == "is:issue":
+		case part == "is:open":
+			params.Set("state", "opened")
+		case strings.HasPrefix(part, "repo:"):
+			repos = append(repos, strings.TrimPrefix(part, "repo:"))
+		default:
+			terms = append(terms, part)
+		}
+	}
+	if len(terms) > 0 {
+		params.Set("search", strings.Join(terms, " "))
+	}
+	params = sinceParams(params, since)
+
+	out := make(chan *IssueResult)
+	go func() {
+		defer close(out)
+		if len(repos) == 0 {
+			params.Set("scope", "all")
+			if err := a.listIssues("issues", params, out); err != nil {
+				out <- &IssueResult{nil, err}
+			}
+			return
+		}
+		for _, repo := range repos {
+			logger.Debugln("GitLab issues for:", repo)
+			err := a.listIssues(fmt.Sprintf("projects/%s/issues", gitlabPath(repo)), params, out)
+			if err != nil {
+				out <- &IssueResult{nil, err}
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ByOrg lists all open issues in a GitLab group
+func (a *GitlabAPI) ByOrg(group string, since time.Time) <-chan *IssueResult {
+	params := sinceParams(url.Values{"state": {"opened"}}, since)
+	out := make(chan *IssueResult)
+	go func() {
+		defer close(out)
+		err := a.listIssues(fmt.Sprintf("groups/%s/issues", gitlabPath(group)), params, out)
+		if err != nil {
+			out <- &IssueResult{nil, err}
+		}
+	}()
+	return out
+}
+
+// ByUser lists open issues assigned to the authenticated user
+func (a *GitlabAPI) ByUser(since time.Time) <-chan *IssueResult {
+	params := sinceParams(url.Values{"state": {"opened"}, "scope": {"assigned_to_me"}}, since)
+	out := make(chan *IssueResult)
+	go func() {
+		defer close(out)
+		if err := a.listIssues("issues", params, out); err != nil {
+			out <- &IssueResult{nil, err}
+		}
+	}()
+	return out
+}
+
+// issuePath is the api path for one of our issues
+func (a *GitlabAPI) issuePath(issue *Issue) string {
+	project := fmt.Sprintf("%s/%s", issue.Owner, issue.Repo)
+	return fmt.Sprintf("projects/%s/issues/%d", gitlabPath(project), issue.Number)
+}
+
+// Get the current state of a single issue
+func (a *GitlabAPI) Get(issue *Issue) (*github.Issue, error) {
+	result := gitlabIssue{}
+	_, err := a.get(a.issuePath(issue), nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	gi := result.githubIssue()
+	return &gi, nil
+}
+
+// Comments on an issue, leaving out the notes GitLab makes itself
+func (a *GitlabAPI) Comments(issue *Issue) ([]*Comment, error) {
+	params := url.Values{"sort": {"asc"}, "per_page": {"100"}}
+	comments := []*Comment{}
+	page := 1
+	for page != 0 {
+		params.Set("page", strconv.Itoa(page))
+		notes := []gitlabNote{}
+		next, err := a.get(a.issuePath(issue)+"/notes", params, &notes)
+		if err != nil {
+			return nil, err
+		}
+		for _, note := range notes {
+			if note.System {
+				continue
+			}
+			comment := &Comment{Author: note.Author.Username, Body: note.Body}
+			if note.CreatedAt != nil {
+				comment.CreatedAt = *note.CreatedAt
+			}
+			comments = append(comments, comment)
+		}
+		page = next
+	}
+	return comments, nil
+}
+
+// Collaborators are the project's members, including those it gets from
+// its groups
+func (a *GitlabAPI) Collaborators(project string) ([]string, error) {
+	params := url.Values{"per_page": {"100"}}
+	logins := []string{}
+	page := 1
+	for page != 0 {
+		params.Set("page", strconv.Itoa(page))
+		members := []gitlabUser{}
+		next, err := a.get(fmt.Sprintf("projects/%s/members/all", gitlabPath(project)), params, &members)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			logins = append(logins, member.Username)
+		}
+		page = next
+	}
+	return logins, nil
+}
+
+// AddComment as a note on the issue
+func (a *GitlabAPI) AddComment(issue *Issue, body string) error {
+	_, err := a.do("POST", a.issuePath(issue)+"/notes", url.Values{"body": {body}}, nil)
+	return err
+}
+
+// editIssue updates an issue with the given params
+func (a *GitlabAPI) editIssue(issue *Issue, params url.Values) error {
+	return a.put(a.issuePath(issue), params, nil)
+}
+
+// SetMilestone sets or clears (if nil) the milestone on an issue
+func (a *GitlabAPI) SetMilestone(issue *Issue, milestone *Milestone) error {
+	// 0 unassigns the milestone
+	id := 0
+	if milestone != nil {
+		id = milestone.Number
+	}
+	return a.editIssue(issue, url.Values{"milestone_id": {strconv.Itoa(id)}})
+}
+
+// ReplaceLabels replaces all the labels on an issue
+func (a *GitlabAPI) ReplaceLabels(issue *Issue, labels []string) error {
+	return a.editIssue(issue, url.Values{"labels": {strings.Join(labels, ",")}})
+}
+
+// AddLabel adds a single label to an issue
+func (a *GitlabAPI) AddLabel(issue *Issue, label string) error {
+	return a.editIssue(issue, url.Values{"add_labels": {label}})
+}
+
+// RemoveLabel removes a single label from an issue
+func (a *GitlabAPI) RemoveLabel(issue *Issue, label string) error {
+	return a.editIssue(issue, url.Values{"remove_labels": {label}})
+}
+
+// SetState opens or closes an issue, using the github names for states
+func (a *GitlabAPI) SetState(issue *Issue, state string) error {
+	event := "reopen"
+	if state == "closed" {
+		event = "close"
+	}
+	return a.editIssue(issue, url.Values{"state_event": {event}})
+}
+
+// SetAssignees replaces the assignees on an issue, GitLab wants user ids
+// so we have to look the logins up first
+func (a *GitlabAPI) SetAssignees(issue *Issue, logins []string) error {
+	params := url.Values{}
+	for _, login := range logins {
+		users := []struct {
+			ID int `json:"id"`
+		}{}
+		_, err := a.get("users", url.Values{"username": {login}}, &users)
+		if err != nil {
+			return err
+		}
+		if len(users) < 1 {
+			return fmt.Errorf("No GitLab user named: %s", login)
+		}
+		params.Add("assignee_ids[]", strconv.Itoa(users[0].ID))
+	}
+	// an empty value unassigns everybody
+	if len(logins) == 0 {
+		params.Set("assignee_ids", "")
+	}
+	return a.editIssue(issue, params)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testGitlab serves the milestones of group/repo and its group a page at a
+// time, the project has 150 of them and only the last is one of our tiers
+func testGitlab(t *testing.T) (*GitlabAPI, *httptest.Server) {
+	due := time.Now().AddDate(0, 0, 3).Format("2006-01-02")
+	project := []gitlabMilestone{}
+	for i := 1; i < 150; i++ {
+		project = append(project, gitlabMilestone{ID: i, Title: fmt.Sprintf("Old %d", i), DueDate: "2001-01-01"})
+	}
+	project = append(project, gitlabMilestone{ID: 150, Title: "Sprint 9", DueDate: due})
+	pages := map[string][]gitlabMilestone{
+		"/api/v4/projects/group%2Frepo/milestones": project,
+		"/api/v4/groups/group/milestones":          {{ID: 200, Title: "Next"}, {ID: 201, Title: "Someday"}},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		all, ok := pages[req.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		page := 1
+		fmt.Sscanf(req.URL.Query().Get("page"), "%d", &page)
+		start, end := (page-1)*100, page*100
+		if end < len(all) {
+			w.Header().Set("X-Next-Page", fmt.Sprintf("%d", page+1))
+		} else {
+			end = len(all)
+		}
+		if err := json.NewEncoder(w).Encode(all[start:end]); err != nil {
+			t.Error(err)
+		}
+	}))
+	config := testConfig()
+	config.Gitlab = GitlabConfig{URL: ts.URL, Projects: Projects{"group/repo"}}
+	return NewGitlabAPI(&http.Client{}, testOptions(), config), ts
+}
+
+func TestGitlabMilestones(t *testing.T) {
+	api, ts := testGitlab(t)
+	defer ts.Close()
+
+	tiers, err := api.Milestones("group/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := []string{}
+	for _, tier := range tiers {
+		titles = append(titles, tier.Title)
+	}
+	if fmt.Sprint(titles) != "[Sprint 9 Next Someday]" {
+		t.Errorf("wrong tiers: %v", titles)
+	}
+
+	// just the project's own
+	open, err := api.OpenMilestones("group/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 150 {
+		t.Errorf("expected 150 open milestones, got %d", len(open))
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/nsf/termbox-go"
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:      "snapshot",
+		Usage:     "drive the ui against a fixture and print (or check) the screen",
+		ArgsUsage: "[target]",
+		Action: func(c *cli.Context) {
+			opts, err := NewOptions(c)
+			if err != nil {
+				logger.Errorln("Invalid options", err)
+				os.Exit(1)
+			}
+			target := c.Args().First()
+			err = cmdSnapshot(
+				opts,
+				target,
+				c.String("fixture"),
+				c.String("keys"),
+				c.Int("width"),
+				c.Int("height"),
+				c.String("golden"),
+				c.Bool("update"),
+			)
+			if err != nil {
+				SoftExit(opts, err)
+			}
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "fixture", Value: "raw_issues.json", Usage: "issues to load, as dumped by --debug"},
+			cli.StringFlag{Name: "keys", Usage: "keys to press, e.g. \"<down>p1\""},
+			cli.IntFlag{Name: "width", Value: 100, Usage: "screen width"},
+			cli.IntFlag{Name: "height", Value: 30, Usage: "screen height"},
+			cli.StringFlag{Name: "golden", Usage: "compare the output to this file"},
+			cli.BoolFlag{Name: "update", Usage: "write the output to the golden file instead"},
+			cli.StringFlag{Name: "org", Usage: "list by org"},
+		},
+	}
+)
+
+// namedKeys are the keys that can be given in <brackets> to --keys
+var namedKeys = map[string]termbox.Key{
+	"up":    termbox.KeyArrowUp,
+	"down":  termbox.KeyArrowDown,
+	"left":  termbox.KeyArrowLeft,
+	"right": termbox.KeyArrowRight,
+	"enter": termbox.KeyEnter,
+	"esc":   termbox.KeyEsc,
+	"space": termbox.KeySpace,
+	"bs":    termbox.KeyBackspace,
+	"pgup":  termbox.KeyPgup,
+	"pgdn":  termbox.KeyPgdn,
+	"tab":   termbox.KeyTab,
+	"c-r":   termbox.KeyCtrlR,
+	"c-s":   termbox.KeyCtrlS,
+}
+
+// parseKeys turns "ab<down>" into key events, a literal "<" is "<<"
+func parseKeys(s string) ([]termbox.Event, error) {
+	events := []termbox.Event{}
+	for len(s) > 0 {
+		if strings.HasPrefix(s, "<<") {
+			events = append(events, termbox.Event{Type: termbox.EventKey, Ch: '<'})
+			s = s[2:]
+			continue
+		}
+		if s[0] == '<' {
+			end := strings.Index(s, ">")
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated key name: %s", s)
+			}
+			name := s[1:end]
+			key, ok := namedKeys[name]
+			if !ok {
+				return nil, fmt.Errorf("Unknown key name: %s", name)
+			}
+			events = append(events, termbox.Event{Type: termbox.EventKey, Key: key})
+			s = s[end+1:]
+			continue
+		}
+		r := []rune(s)[0]
+		if r == ' ' {
+			events = append(events, termbox.Event{Type: termbox.EventKey, Key: termbox.KeySpace})
+		} else {
+			events = append(events, termbox.Event{Type: termbox.EventKey, Ch: r})
+		}
+		s = s[len(string(r)):]
+	}
+	return events, nil
+}
+
+// cmdSnapshot runs the ui headless against a FakeAPI, presses some keys and
+// outputs the rendered screen followed by the calls made to the API
+func cmdSnapshot(opts *Options, target, fixture, keys string, width, height int, golden string, update bool) error {
+	events, err := parseKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	config, err := LoadConfig(opts)
+	if err != nil {
+		return err
+	}
+	api, err := LoadFakeAPI(fixture)
+	if err != nil {
+		return err
+	}
+
+	out, err := snapshot(opts, config, api, target, events, width, height)
+	if err != nil {
+		return err
+	}
+
+	if golden == "" {
+		fmt.Print(out)
+		return nil
+	}
+	if update {
+		return ioutil.WriteFile(golden, []byte(out), 0666)
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		return err
+	}
+	if diff := lineDiff(string(expected), out); diff != "" {
+		return fmt.Errorf("Snapshot does not match %s:\n%s", golden, diff)
+	}
+	return nil
+}
+
+// snapshot drives a TopIssueWindow on a CellScreen, the tests use it too
+func snapshot(opts *Options, config *Config, api *FakeAPI, target string, events []termbox.Event, width, height int) (string, error) {
+	cells := NewCellScreen(width, height)
+	screen = cells
+	defer func() { screen = termboxScreen{} }()
+
+	issueWindow := NewTopIssueWindow(opts, config, api, target)
+	if err := issueWindow.Init(); err != nil {
+		return "", err
+	}
+	issueWindow.Wait()
+	issueWindow.Redraw()
+
+	for _, ev := range events {
+		_, err := issueWindow.HandleEvent(ev)
+		if err != nil {
+			return "", err
+		}
+		issueWindow.Redraw()
+		// anything fetched in the background redraws when it's done
+		issueWindow.Wait()
+	}
+
+	out := cells.String()
+	out += "--- calls\n"
+	for _, call := range api.Calls {
+		out += call + "\n"
+	}
+	return out, nil
+}
+
+// lineDiff is a very dumb line-by-line diff, good enough for screens
+func lineDiff(a, b string) string {
+	as := strings.Split(a, "\n")
+	bs := strings.Split(b, "\n")
+	diff := ""
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var al, bl string
+		if i < len(as) {
+			al = as[i]
+		}
+		if i < len(bs) {
+			bl = bs[i]
+		}
+		if al != bl {
+			diff += fmt.Sprintf("%3d - %s\n%3d + %s\n", i+1, al, i+1, bl)
+		}
+	}
+	return diff
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/nsf/termbox-go"
@@ -24,16 +25,20 @@ type Window interface {
 // Issue is the data we care about from the github.Issue, plus some of our own
 type Issue struct {
 	Milestone *IssueMilestone
-	Priority  *IssuePriority
-	Type      *IssueType
-	Number    int
-	Title     string
-	Body      string
-	URL       string
-	Owner     string
-	Repo      string
-	Project   string
-	Labels    []string
+	// Dimensions are in the same order as the config's
+	Dimensions []*IssueLabel
+	Number     int
+	Title      string
+	Body       string
+	URL        string
+	Owner      string
+	Repo       string
+	Project    string
+	Labels     []string
+	Author     string
+	Assignees  []string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 // IssueMilestone sortable milestone
@@ -42,16 +47,36 @@ type IssueMilestone struct {
 	*Milestone
 }
 
-// IssuePriority sortable priority
-type IssuePriority struct {
-	Index int
-	*Priority
+// IssueLabel sortable label from one of the dimensions
+type IssueLabel struct {
+	Index     int
+	Dimension string
+	Hotkey    string
+	Idx       bool
+	*Label
 }
 
-// IssueType sortable type
-type IssueType struct {
-	Index int
-	*Type
+// Label is the issue's label for a dimension, with an Index of 0 if it
+// has none
+func (i *Issue) Label(dimension string) *IssueLabel {
+	for _, l := range i.Dimensions {
+		if strings.EqualFold(l.Dimension, dimension) {
+			return l
+		}
+	}
+	return &IssueLabel{Dimension: dimension}
+}
+
+// Idx is the milestone number followed by the number of each dimension
+// that's part of the idx
+func (i *Issue) Idx() string {
+	idx := fmt.Sprintf("%d", i.Milestone.Index)
+	for _, l := range i.Dimensions {
+		if l.Idx {
+			idx += fmt.Sprintf("%d", l.Index)
+		}
+	}
+	return idx
 }
 
 // IssueResult is for loading issues iteratively
@@ -61,7 +86,7 @@ type IssueResult struct {
 }
 
 // NewIssue constructor for an Issue from a github.Issue
-func NewIssue(issue github.Issue, ms map[string][]*Milestone, ps []Priority, ts []Type) *Issue {
+func NewIssue(issue github.Issue, ms map[string][]*Milestone, tiers []MilestoneTier, dimensions []Dimension) *Issue {
 	number := *issue.Number
 	title := *issue.Title
 	body := ""
@@ -72,44 +97,45 @@ func NewIssue(issue github.Issue, ms map[string][]*Milestone, ps []Priority, ts
 	url := *issue.HTMLURL
 	owner, repo, _ := ownerRepoFromURL(url)
 	project := fmt.Sprintf("%s/%s", owner, repo)
+	// projects on other hosts are configured as host/owner/repo
+	if qualified := fmt.Sprintf("%s/%s", hostFromURL(url), project); ms[qualified] != nil {
+		project = qualified
+	}
 
 	var issueMilestone IssueMilestone
-	var issuePriority IssuePriority
-	var issueType IssueType
 
-	// figure out the milestone based on milestone number
+	// figure out the milestone based on milestone number, one that isn't
+	// ours is still remembered but sorts as untriaged
 	issueMilestone = IssueMilestone{Index: 0}
 	if issue.Milestone != nil {
 		mNumber := *issue.Milestone.Number
+		issueMilestone.Milestone = &Milestone{
+			Number: mNumber,
+			Title:  *issue.Milestone.Title,
+			DueOn:  issue.Milestone.DueOn,
+		}
 		if ourMs := ms[project]; ourMs != nil {
 			for i, m := range ourMs {
-				if m.Number == mNumber {
-					issueMilestone = IssueMilestone{Index: i + 1, Milestone: m}
+				if m != nil && m.Number == mNumber {
+					issueMilestone = IssueMilestone{Index: tierIndex(tiers, i), Milestone: m}
 				}
 			}
 		}
 	}
 
-	// figure out the priority based on label name
-	issuePriority = IssuePriority{Index: 0}
-	for i, p := range ps {
-		for _, l := range issue.Labels {
-			if p.Name == *l.Name {
-				issuePriority = IssuePriority{Index: i + 1, Priority: &p}
-				break
-			}
-		}
-	}
-
-	// figure out the type based on label name
-	issueType = IssueType{Index: 0}
-	for i, t := range ts {
-		for _, l := range issue.Labels {
-			if t.Name == *l.Name {
-				issueType = IssueType{Index: i + 1, Type: &t}
-				break
+	// figure out each dimension based on label name
+	issueLabels := []*IssueLabel{}
+	for _, d := range dimensions {
+		issueLabel := newIssueLabel(d, 0)
+		for i, label := range d.Labels {
+			for _, l := range issue.Labels {
+				if label.Matches(*l.Name) {
+					issueLabel = newIssueLabel(d, i+1)
+					break
+				}
 			}
 		}
+		issueLabels = append(issueLabels, issueLabel)
 	}
 
 	// set the labels
@@ -118,23 +144,83 @@ func NewIssue(issue github.Issue, ms map[string][]*Milestone, ps []Priority, ts
 		labels = append(labels, *label.Name)
 	}
 
+	// and who it's from and for, older issues only have the one assignee
+	author := ""
+	if issue.User != nil && issue.User.Login != nil {
+		author = *issue.User.Login
+	}
+	assignees := []string{}
+	for _, user := range issue.Assignees {
+		if user != nil && user.Login != nil {
+			assignees = append(assignees, *user.Login)
+		}
+	}
+	if len(assignees) == 0 && issue.Assignee != nil && issue.Assignee.Login != nil {
+		assignees = append(assignees, *issue.Assignee.Login)
+	}
+	var createdAt, updatedAt time.Time
+	if issue.CreatedAt != nil {
+		createdAt = *issue.CreatedAt
+	}
+	if issue.UpdatedAt != nil {
+		updatedAt = *issue.UpdatedAt
+	}
+
 	return &Issue{
-		Milestone: &issueMilestone,
-		Priority:  &issuePriority,
-		Type:      &issueType,
-		Number:    number,
-		Title:     title,
-		Body:      body,
-		URL:       url,
-		Owner:     owner,
-		Repo:      repo,
-		Project:   project,
-		Labels:    labels,
-	}
-}
-
-// Search uses the Github search API
-func (a *GithubAPI) Search(query string) <-chan *IssueResult {
+		Milestone:  &issueMilestone,
+		Dimensions: issueLabels,
+		Number:     number,
+		Title:      title,
+		Body:       body,
+		URL:        url,
+		Owner:      owner,
+		Repo:       repo,
+		Project:    project,
+		Labels:     labels,
+		Author:     author,
+		Assignees:  assignees,
+		CreatedAt:  createdAt,
+		UpdatedAt:  updatedAt,
+	}
+}
+
+// tierIndex is the idx number of the i'th milestone tier
+func tierIndex(tiers []MilestoneTier, i int) int {
+	if i < len(tiers) {
+		return tiers[i].Index
+	}
+	return i + 1
+}
+
+// newIssueLabel for the index'th label of a dimension, 0 being none
+func newIssueLabel(d Dimension, index int) *IssueLabel {
+	issueLabel := &IssueLabel{Index: index, Dimension: d.Name, Hotkey: d.Hotkey, Idx: d.Idx}
+	if index > 0 {
+		issueLabel.Label = &d.Labels[index-1]
+	}
+	return issueLabel
+}
+
+// sinceQuery rewrites a search query to find everything, open or closed,
+// that has been updated since a time
+func sinceQuery(query string, since time.Time) string {
+	if since.IsZero() {
+		return query
+	}
+	parts := []string{}
+	for _, part := range strings.Fields(query) {
+		if part != "is:open" {
+			parts = append(parts, part)
+		}
+	}
+	parts = append(parts, fmt.Sprintf("updated:>=%s", since.UTC().Format("2006-01-02T15:04:05Z")))
+	return strings.Join(parts, " ")
+}
+
+// Search uses the Github search API, if since is set closed issues are
+// included too
+func (a *GithubAPI) Search(query string, since time.Time) <-chan *IssueResult {
+	query = sinceQuery(query, since)
 	params := &github.SearchOptions{
 		Order:       "updated",
 		ListOptions: github.ListOptions{PerPage: 100},
@@ -146,6 +232,7 @@ func (a *GithubAPI) Search(query string) <-chan *IssueResult {
 			result, resp, err := a.client.Search.Issues(query, params)
 			if err != nil {
 				out <- &IssueResult{nil, err}
+				return
 			}
 			out <- &IssueResult{result.Issues, nil}
 			if resp.NextPage == 0 {
@@ -157,13 +244,18 @@ func (a *GithubAPI) Search(query string) <-chan *IssueResult {
 	return out
 }
 
-// ByOrg lists all issues by org
-func (a *GithubAPI) ByOrg(query string) <-chan *IssueResult {
+// ByOrg lists all issues by org, if since is set closed issues are
+// included too
+func (a *GithubAPI) ByOrg(query string, since time.Time) <-chan *IssueResult {
 	params := &github.IssueListOptions{
 		Filter:      "all",
 		Sort:        "updated",
 		ListOptions: github.ListOptions{PerPage: 1000},
 	}
+	if !since.IsZero() {
+		params.State = "all"
+		params.Since = since
+	}
 	out := make(chan *IssueResult)
 	go func() {
 		defer close(out)
@@ -172,6 +264,7 @@ func (a *GithubAPI) ByOrg(query string) <-chan *IssueResult {
 			issues, resp, err := a.client.Issues.ListByOrg(query, params)
 			if err != nil {
 				out <- &IssueResult{nil, err}
+				return
 			}
 			out <- &IssueResult{issues, nil}
 			if resp.NextPage == 0 {
@@ -183,13 +276,18 @@ func (a *GithubAPI) ByOrg(query string) <-chan *IssueResult {
 	return out
 }
 
-// ByUser lists issues assigned to authenticated user
-func (a *GithubAPI) ByUser() <-chan *IssueResult {
+// ByUser lists issues assigned to authenticated user, if since is set
+// closed issues are included too
+func (a *GithubAPI) ByUser(since time.Time) <-chan *IssueResult {
 	params := &github.IssueListOptions{
 		// Filter:      "all",
 		Sort:        "updated",
 		ListOptions: github.ListOptions{PerPage: 1000},
 	}
+	if !since.IsZero() {
+		params.State = "all"
+		params.Since = since
+	}
 
 	out := make(chan *IssueResult)
 	go func() {
@@ -199,6 +297,7 @@ func (a *GithubAPI) ByUser() <-chan *IssueResult {
 			issues, resp, err := a.client.Issues.List(true, params)
 			if err != nil {
 				out <- &IssueResult{nil, err}
+				return
 			}
 			out <- &IssueResult{issues, nil}
 			if resp.NextPage == 0 {
@@ -210,6 +309,59 @@ func (a *GithubAPI) ByUser() <-chan *IssueResult {
 	return out
 }
 
+// Get the current state of a single issue
+func (a *GithubAPI) Get(issue *Issue) (*github.Issue, error) {
+	result, _, err := a.client.Issues.Get(issue.Owner, issue.Repo, issue.Number)
+	return result, err
+}
+
+// SetMilestone sets or clears (if nil) the milestone on an issue
+func (a *GithubAPI) SetMilestone(issue *Issue, milestone *Milestone) error {
+	if milestone != nil {
+		_, _, err := a.client.Issues.Edit(issue.Owner, issue.Repo, issue.Number, &github.IssueRequest{Milestone: &milestone.Number})
+		return err
+	}
+
+	// IssueRequest omits a nil milestone, so send the null ourselves
+	u := fmt.Sprintf("repos/%v/%v/issues/%d", issue.Owner, issue.Repo, issue.Number)
+	req, err := a.client.NewRequest("PATCH", u, map[string]interface{}{"milestone": nil})
+	if err != nil {
+		return err
+	}
+	_, err = a.client.Do(req, nil)
+	return err
+}
+
+// ReplaceLabels replaces all the labels on an issue
+func (a *GithubAPI) ReplaceLabels(issue *Issue, labels []string) error {
+	_, _, err := a.client.Issues.ReplaceLabelsForIssue(issue.Owner, issue.Repo, issue.Number, labels)
+	return err
+}
+
+// AddLabel adds a single label to an issue
+func (a *GithubAPI) AddLabel(issue *Issue, label string) error {
+	_, _, err := a.client.Issues.AddLabelsToIssue(issue.Owner, issue.Repo, issue.Number, []string{label})
+	return err
+}
+
+// RemoveLabel removes a single label from an issue
+func (a *GithubAPI) RemoveLabel(issue *Issue, label string) error {
+	_, err := a.client.Issues.RemoveLabelForIssue(issue.Owner, issue.Repo, issue.Number, label)
+	return err
+}
+
+// SetState opens or closes an issue
+func (a *GithubAPI) SetState(issue *Issue, state string) error {
+	_, _, err := a.client.Issues.Edit(issue.Owner, issue.Repo, issue.Number, &github.IssueRequest{State: &state})
+	return err
+}
+
+// SetAssignees replaces the assignees on an issue
+func (a *GithubAPI) SetAssignees(issue *Issue, logins []string) error {
+	_, _, err := a.client.Issues.Edit(issue.Owner, issue.Repo, issue.Number, &github.IssueRequest{Assignees: &logins})
+	return err
+}
+
 // RepoSort sorts by repo name then triagesort
 func RepoSort(i, j *Issue) bool {
 	if i.Repo == j.Repo {
@@ -236,12 +388,12 @@ func TitleSort(i, j *Issue) bool {
 
 // TriageSortLess sorts in order of:
 // 1. Anything with Priority 1
-// 2. By TriageNumber (MilestonePriorityType)
+// 2. By TriageNumber (the Idx, e.g. MilestonePriorityType)
 func TriageSort(i, j *Issue) bool {
-	iNumber, _ := strconv.Atoi(fmt.Sprintf("%d%d%d", i.Milestone.Index, i.Priority.Index, i.Type.Index))
-	jNumber, _ := strconv.Atoi(fmt.Sprintf("%d%d%d", j.Milestone.Index, j.Priority.Index, j.Type.Index))
-	iPri := i.Priority.Index
-	jPri := j.Priority.Index
+	iNumber, _ := strconv.Atoi(i.Idx())
+	jNumber, _ := strconv.Atoi(j.Idx())
+	iPri := i.Label("priority").Index
+	jPri := j.Label("priority").Index
 
 	// tiebreaker
 	if iNumber == jNumber {
@@ -286,9 +438,16 @@ func (w *Subwindow) HandleEvent(ev termbox.Event) (bool, error) {
 	return false, nil
 }
 
+// idxColumn is how wide the idx column is, never narrower than "idx"
+func (w *Subwindow) idxColumn() int {
+	if width := w.Config.IdxWidth(); width > 3 {
+		return width
+	}
+	return 3
+}
+
 // TopIssueWindow is the Top Level Window
 type TopIssueWindow struct {
-	Client      *github.Client
 	Opts        *Options
 	Config      *Config
 	API         API
@@ -303,11 +462,15 @@ type TopIssueWindow struct {
 	SortFunc    func(*Issue, *Issue) bool
 	SortAsc     bool
 	drawSync    sync.Mutex
+	loading     sync.WaitGroup
+	UseCache    bool
+	Cache       *Cache
+	Syncing     bool
+	Journal     *Journal
 
 	// Milestones are weird
 	Milestones map[string][]*Milestone
-	Priorities []Priority
-	Types      []Type
+	Dimensions []Dimension
 
 	// Sub-Windows
 	Help              Window
@@ -317,16 +480,19 @@ type TopIssueWindow struct {
 	List              Window
 	ListMenu          Window
 	ListMilestoneMenu Window
-	ListPriorityMenu  Window
-	ListTypeMenu      Window
-	AlertModal        Window
-	StatusLine        Window
+	// ListLabelMenus are in the same order as the Dimensions
+	ListLabelMenus  []Window
+	ListCommentMenu Window
+	Detail          Window
+	Compose         Window
+	Assign          Window
+	AlertModal      Window
+	StatusLine      Window
 }
 
 // NewTopIssueWindow ctor
-func NewTopIssueWindow(client *github.Client, opts *Options, config *Config, api API, target string) *TopIssueWindow {
+func NewTopIssueWindow(opts *Options, config *Config, api API, target string) *TopIssueWindow {
 	return &TopIssueWindow{
-		Client: client,
 		Opts:   opts,
 		Config: config,
 		API:    api,
@@ -340,48 +506,32 @@ func (w *TopIssueWindow) Init() error {
 	w.drawSync.Lock()
 	defer w.drawSync.Unlock()
 
-	termbox.SetOutputMode(termbox.Output256)
+	screen.SetOutputMode(termbox.Output256)
 
-	// Decide what to search for
-	// 1. if org is specified, use that
-	// 2. if target is specified, use that
-	// 3. if no target is specified but projects are configued, use that
-	// 4. if no target and no projects, list by user
-	org := w.Opts.CLI.String("org")
-	if org != "" {
-		w.Org = org
-	} else {
-		// build our search string
-		target := "is:open is:issue"
-		if w.Target == "" {
-			if len(w.Config.Projects) > 0 {
-				for _, project := range w.Config.Projects {
-					target += fmt.Sprintf(" repo:%s", project)
-				}
-				w.Target = target
-			} else {
-				w.Target = ""
-			}
+	w.Org, w.Target = resolveTarget(w.Config, w.Opts.CLI.String("org"), w.Target)
+
+	// start from whatever we remember from last time
+	w.Cache = &Cache{}
+	if w.UseCache {
+		cache, err := LoadCache(w.cacheKey())
+		if err != nil {
+			logger.Warnln("Ignoring cache:", err)
 		} else {
-			target += fmt.Sprintf(" %s", w.Target)
-			w.Target = target
+			w.Cache = cache
 		}
 	}
 
-	// build our milestones, priorities, types
-	milestones := map[string][]*Milestone{}
-	for _, project := range w.Config.Projects {
-		resp, err := w.API.Milestones(project)
-		if err == nil {
-			// NOTE(termie): ignoring this error in case people don't use milestones
-			//               code later on down the line should fail gracefully if
-			//               a milestone operation is attempted
-			milestones[project] = resp
-		}
+	if w.Journal != nil && len(w.Cache.Issues) == 0 {
+		return fmt.Errorf("Nothing cached to use offline for: %s", w.cacheKey())
 	}
-	w.Milestones = milestones
-	w.Priorities = w.Config.Priorities
-	w.Types = w.Config.Types
+
+	// build our milestones and dimensions
+	if w.Cache.Milestones != nil {
+		w.Milestones = w.Cache.Milestones
+	} else {
+		w.Milestones = w.fetchMilestones()
+	}
+	w.Dimensions = w.Config.Dimensions
 
 	list := NewListWindow(w)
 
@@ -393,23 +543,35 @@ func (w *TopIssueWindow) Init() error {
 	w.StatusLine = NewStatusWindow(w)
 	w.ListMenu = NewListMenu(list)
 	w.ListMilestoneMenu = NewListMilestoneMenu(list)
-	w.ListPriorityMenu = NewListPriorityMenu(list)
-	w.ListTypeMenu = NewListTypeMenu(list)
+	w.ListLabelMenus = []Window{}
+	for i := range w.Dimensions {
+		w.ListLabelMenus = append(w.ListLabelMenus, NewListLabelMenu(list, i))
+	}
+	w.ListCommentMenu = NewListCommentMenu(list)
+	w.Detail = NewDetailWindow(list)
+	w.Compose = NewComposeWindow(list)
+	w.Assign = NewAssignWindow(list)
 	w.AlertModal = NewAlertWindow(w)
 
-	for _, win := range []Window{
+	windows := []Window{
 		w.Help,
 		w.Header,
 		w.List,
 		w.ListMenu,
 		w.ListMilestoneMenu,
-		w.ListPriorityMenu,
-		w.ListTypeMenu,
+	}
+	windows = append(windows, w.ListLabelMenus...)
+	windows = append(windows,
+		w.ListCommentMenu,
+		w.Detail,
+		w.Compose,
+		w.Assign,
 		w.FilterLine,
 		w.SortLine,
 		w.StatusLine,
 		w.AlertModal,
-	} {
+	)
+	for _, win := range windows {
 		err := win.Init()
 		if err != nil {
 			return err
@@ -422,16 +584,103 @@ func (w *TopIssueWindow) Init() error {
 	return nil
 }
 
+// resolveTarget decides what to search for
+// 1. if org is specified, use that
+// 2. if target is specified, use that
+// 3. if no target is specified but projects are configued, use that
+// 4. if no target and no projects, list by user
+func resolveTarget(config *Config, org, target string) (string, string) {
+	if org != "" {
+		return org, target
+	}
+	// build our search string
+	search := "is:open is:issue"
+	if target != "" {
+		return "", fmt.Sprintf("%s %s", search, target)
+	}
+	projects := config.AllProjects()
+	if len(projects) == 0 {
+		return "", ""
+	}
+	for _, project := range projects {
+		// github rejects its own host in a repo: so drop it
+		if host, rest := splitProject(project); host == config.GithubHost() {
+			project = rest
+		}
+		search += fmt.Sprintf(" repo:%s", project)
+	}
+	return "", search
+}
+
+// fetchResults lists issues for whatever resolveTarget decided on
+func fetchResults(api API, org, target string, since time.Time) <-chan *IssueResult {
+	if org != "" {
+		return api.ByOrg(org, since)
+	} else if target != "" {
+		return api.Search(target, since)
+	}
+	return api.ByUser(since)
+}
+
+// cacheKey identifies what we're looking at for the cache
+func (w *TopIssueWindow) cacheKey() string {
+	if w.Org != "" {
+		return fmt.Sprintf("org:%s", w.Org)
+	}
+	if w.Target != "" {
+		return fmt.Sprintf("search:%s", w.Target)
+	}
+	return "user"
+}
+
+// fetchMilestones for all our configured projects
+func (w *TopIssueWindow) fetchMilestones() map[string][]*Milestone {
+	return fetchMilestones(w.API, w.Config)
+}
+
+// fetchMilestones for all the projects in a config
+func fetchMilestones(api API, config *Config) map[string][]*Milestone {
+	milestones := map[string][]*Milestone{}
+	for _, project := range config.AllProjects() {
+		resp, err := api.Milestones(project)
+		if err != nil {
+			// NOTE(termie): ignoring this error in case people don't use milestones
+			//               code later on down the line should fail gracefully if
+			//               a milestone operation is attempted
+			logger.Warnln(err)
+		}
+		// a project missing some tiers still gets the ones it has
+		if resp != nil {
+			milestones[project] = resp
+		}
+	}
+	return milestones
+}
+
+// Wait until the initial issues have been fetched
+func (w *TopIssueWindow) Wait() {
+	w.loading.Wait()
+}
+
 // Draw all the subwindows
 func (w *TopIssueWindow) Draw(x, y, x1, y1 int) {
 	w.Status = ""
 	w.Header.Draw(x, y, x1, y)
-	w.SortLine.Draw(x, y+1, x1, y+1)
-	w.FilterLine.Draw(x, y+2, x1, y+2)
-	if w.ContextMenu != nil {
-		w.ContextMenu.Draw(x, y+3, x1, y+3)
+	switch w.Focus {
+	case w.Detail:
+		w.Detail.Draw(x, y+1, x1, y1-2)
+	case w.Compose:
+		w.Compose.Draw(x, y+1, x1, y1-2)
+	case w.Assign:
+		w.Assign.Draw(x, y+1, x1, y1-2)
+	default:
+		w.SortLine.Draw(x, y+1, x1, y+1)
+		w.FilterLine.Draw(x, y+2, x1, y+2)
+		if w.ContextMenu != nil {
+			w.ContextMenu.Draw(x, y+3, x1, y+3)
+		}
+		w.List.Draw(x, y+4, x1, y1-2)
 	}
-	w.List.Draw(x, y+4, x1, y1-2)
 	w.StatusLine.Draw(x, y1-1, x1, y1-1)
 	w.Help.Draw(x, y, x1, y1)
 	w.AlertModal.Draw(x, y, x1, y1)
@@ -441,10 +690,10 @@ func (w *TopIssueWindow) Draw(x, y, x1, y1 int) {
 func (w *TopIssueWindow) Redraw() {
 	w.drawSync.Lock()
 	defer w.drawSync.Unlock()
-	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
-	width, height := termbox.Size()
+	screen.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	width, height := screen.Size()
 	w.Draw(0, 0, width, height)
-	termbox.Flush()
+	screen.Flush()
 }
 
 // HandleEvent passes events to the subwindows
@@ -510,32 +759,39 @@ func (w *HelpWindow) Draw(x, y, x1, y1 int) {
 		return
 	}
 
-	width, height := termbox.Size()
-	buffer := termbox.CellBuffer()
+	width, height := screen.Size()
+	buffer := screen.CellBuffer()
 	// dim the background
 	for ix := 0; ix < width; ix++ {
 		for iy := 0; iy < height; iy++ {
 			cell := buffer[iy*width+ix]
-			termbox.SetCell(ix, iy, cell.Ch, 235, cell.Bg)
+			screen.SetCell(ix, iy, cell.Ch, 235, cell.Bg)
 		}
 	}
 
-	// our overlay
+	// our overlay, the idx is as wide as our dimensions make it
+	idx := w.idxColumn()
+	pad := strings.Repeat(" ", idx-3)
 	overlay := `
          **********************************************************************
             ******************            ↳the current github search query
               ↳sort +/- by a column
-   ↙  ↙  ↙   ↙
-  *** ****  ***  *****
+   ↙  ` + pad + `    ↙  ↙   ↙
+  ` + strings.Repeat("*", idx) + ` *** ****  ***  *****
 
   ↙this number represents your milestone (0 means unassigned)
   *
-   ↙this number represents your priority
-   *
-    ↙this number represents your type
-    *
-  *** ←together they are a sortable index, showing you the most relevant issues
 `
+	column := 3
+	for _, d := range w.Dimensions {
+		if !d.Idx {
+			continue
+		}
+		indent := strings.Repeat(" ", column)
+		overlay += fmt.Sprintf("%s↙this number represents your %s\n%s*\n", indent, d.Name, indent)
+		column++
+	}
+	overlay += "  " + strings.Repeat("*", idx) + " ←together they are a sortable index, showing you the most relevant issues\n"
 	lines := strings.Split(overlay, "\n")
 	lines = lines[1:]
 	for iy, line := range lines {
@@ -549,7 +805,7 @@ func (w *HelpWindow) Draw(x, y, x1, y1 int) {
 			} else if c == ' ' {
 				continue
 			}
-			termbox.SetCell(ix, iy, c, fg, bg)
+			screen.SetCell(ix, iy, c, fg, bg)
 		}
 	}
 }
@@ -583,13 +839,13 @@ func (w *AlertWindow) Draw(x, y, x1, y1 int) {
 		return
 	}
 
-	width, height := termbox.Size()
-	buffer := termbox.CellBuffer()
+	width, height := screen.Size()
+	buffer := screen.CellBuffer()
 	// dim the background
 	for ix := 0; ix < width; ix++ {
 		for iy := 0; iy < height; iy++ {
 			cell := buffer[iy*width+ix]
-			termbox.SetCell(ix, iy, cell.Ch, 235, cell.Bg)
+			screen.SetCell(ix, iy, cell.Ch, 235, cell.Bg)
 		}
 	}
 
@@ -613,7 +869,7 @@ func (w *AlertWindow) Draw(x, y, x1, y1 int) {
 			if c == ' ' {
 				continue
 			}
-			termbox.SetCell(ix+startX, iy+startY, c, fg, bg)
+			screen.SetCell(ix+startX, iy+startY, c, fg, bg)
 		}
 	}
 }
@@ -652,6 +908,12 @@ func (w *HeaderWindow) Draw(x, y, x1, y1 int) {
 		title = fmt.Sprintf("assigned issues for authenticated user")
 	}
 
+	if w.Journal != nil {
+		title += fmt.Sprintf(" (offline, %d queued)", len(w.Journal.Entries))
+	} else if w.Syncing {
+		title += " (syncing)"
+	}
+
 	printLine(fmt.Sprintf("*triage* %s", title), x, y)
 }
 
@@ -670,12 +932,15 @@ func NewStatusWindow(w *TopIssueWindow) *StatusWindow {
 
 // Draw the status line
 func (w *StatusWindow) Draw(x, y, x1, y1 int) {
+	if rate := githubRates.String(); rate != "" {
+		printLine(rate, x1-len(rate)-1, y)
+	}
 	if w.Focus != w {
 		printLine(fmt.Sprintf("[:] %s", w.Status), x, y)
 		return
 	}
 	printLine(fmt.Sprintf(":%s", w.Buffer), x, y)
-	termbox.SetCursor(x+1+len(w.Buffer), y)
+	screen.SetCursor(x+1+len(w.Buffer), y)
 }
 
 // HandleEvent for our vim-style exit keys
@@ -684,7 +949,7 @@ func (w *StatusWindow) HandleEvent(ev termbox.Event) (bool, error) {
 	case termbox.EventKey:
 		switch ev.Key {
 		case termbox.KeyEsc:
-			termbox.HideCursor()
+			screen.HideCursor()
 			w.Focus = w.List
 			w.ContextMenu = w.ListMenu
 			return true, nil
@@ -694,7 +959,7 @@ func (w *StatusWindow) HandleEvent(ev termbox.Event) (bool, error) {
 				w.Buffer = w.Buffer[:len(w.Buffer)-1]
 				return true, nil
 			}
-			termbox.HideCursor()
+			screen.HideCursor()
 			w.Focus = w.List
 			w.ContextMenu = w.ListMenu
 			return true, nil
@@ -756,8 +1021,8 @@ func (w *FilterWindow) Draw(x, y, x1, y1 int) {
 	printLineColor(w.Filter, x+1+len(pre), y, fg, bg)
 	if w.Focus == w {
 		for i := x + 1 + len(pre) + len(w.Filter); i < 60; i++ {
-			termbox.SetCell(x+i, y, ' ', fg, bg)
-			termbox.SetCursor(x+1+len(pre)+len(w.Filter), y)
+			screen.SetCell(x+i, y, ' ', fg, bg)
+			screen.SetCursor(x+1+len(pre)+len(w.Filter), y)
 		}
 	}
 
@@ -770,14 +1035,14 @@ func (w *FilterWindow) HandleEvent(ev termbox.Event) (bool, error) {
 	case termbox.EventKey:
 		switch ev.Key {
 		case termbox.KeyArrowUp:
-			termbox.HideCursor()
+			screen.HideCursor()
 			w.Focus = w.SortLine
 			w.ContextMenu = nil
 			return true, nil
 		case termbox.KeyArrowDown:
 			fallthrough
 		case termbox.KeyEsc:
-			termbox.HideCursor()
+			screen.HideCursor()
 			w.Focus = w.List
 			w.ContextMenu = w.ListMenu
 			return true, nil
@@ -847,8 +1112,8 @@ func (w *SortWindow) Draw(x, y, x1, y1 int) {
 	printLineColor(w.Sort, x+1+len(pre), y, fg, bg)
 	if w.Focus == w {
 		for i := x + 1 + len(pre) + len(w.Sort); i < x+30; i++ {
-			termbox.SetCell(x+i, y, ' ', fg, bg)
-			termbox.SetCursor(x+1+len(pre)+len(w.Sort), y)
+			screen.SetCell(x+i, y, ' ', fg, bg)
+			screen.SetCursor(x+1+len(pre)+len(w.Sort), y)
 		}
 	}
 	printLine(" [?] help [^C] exit", x+30, y)
@@ -895,38 +1160,37 @@ func (w *SortWindow) update(s string) {
 	if len(s) < 2 {
 		return
 	}
+	sortFunc, asc, ok := parseSort(s)
+	w.SortFunc = sortFunc
+	w.valid = ok
+	if ok {
+		w.SortAsc = asc
+	}
+}
+
+// parseSort turns a sort string like "-repo" into a sort func and
+// whether it's ascending
+func parseSort(s string) (func(*Issue, *Issue) bool, bool, bool) {
 	s = strings.ToLower(s)
 
 	asc := true
-	if s[0] == '-' {
+	if strings.HasPrefix(s, "-") {
 		asc = false
 		s = s[1:]
-	} else if s[0] == '+' {
+	} else if strings.HasPrefix(s, "+") {
 		s = s[1:]
 	}
 
-	switch s {
-	case "idx":
-		w.SortFunc = TriageSort
-		w.valid = true
-		w.SortAsc = asc
-	case "repo":
-		w.SortFunc = RepoSort
-		w.valid = true
-		w.SortAsc = asc
-	case "num":
-		w.SortFunc = NumberSort
-		w.valid = true
-		w.SortAsc = asc
-	case "title":
-		w.SortFunc = TitleSort
-		w.valid = true
-		w.SortAsc = asc
-	default:
-		w.SortFunc = nil
-		w.valid = false
-	}
+	sortFunc, ok := sortFuncs[s]
+	return sortFunc, asc, ok
+}
 
+// sortFuncs are the sort keys you can type in the sort box
+var sortFuncs = map[string]func(*Issue, *Issue) bool{
+	"idx":   TriageSort,
+	"repo":  RepoSort,
+	"num":   NumberSort,
+	"title": TitleSort,
 }
 
 // Context menus for the issue list
@@ -952,12 +1216,11 @@ func (w *ListMenu) Draw(x, y, x1, y1 int) {
 		return
 	}
 
-	expand := "expand"
-	if w.expanding {
-		expand = "collapse"
+	menu := "[m] set milestone"
+	for _, d := range w.Dimensions {
+		menu += fmt.Sprintf(" [%s] set %s", d.Hotkey, d.Name)
 	}
-
-	printLine(fmt.Sprintf("[m] set milestone [p] set priority [t] set type [enter] %s", expand), x+2, y)
+	printLine(fmt.Sprintf("%s [a] assign [c] comment [enter] details [space] mark [*] mark all [u] undo", menu), x+2, y)
 }
 
 // HandleEvent for the menu
@@ -966,20 +1229,34 @@ func (w *ListMenu) HandleEvent(ev termbox.Event) (bool, error) {
 	case termbox.EventKey:
 		switch ev.Key {
 		case termbox.KeyEnter:
-			w.expanding = !w.expanding
+			if len(w.currentIssues) > 0 {
+				w.Focus = w.Detail
+			}
 			return true, nil
 		default:
-			switch ev.Ch {
-			case 'm':
+			if ev.Ch == 'm' {
 				w.ContextMenu = w.ListMilestoneMenu
 				return true, nil
-			case 'p':
-				w.ContextMenu = w.ListPriorityMenu
+			}
+			if ev.Ch == 'a' {
+				w.assign(w.targets())
 				return true, nil
-			case 't':
-				w.ContextMenu = w.ListTypeMenu
+			}
+			if ev.Ch == 'c' {
+				// a second "c" writes one
+				if w.ContextMenu == w.ListCommentMenu {
+					w.compose(w.targets(), "")
+					return true, nil
+				}
+				w.ContextMenu = w.ListCommentMenu
 				return true, nil
 			}
+			for i, d := range w.Dimensions {
+				if ev.Ch != 0 && ev.Ch == d.Key() {
+					w.ContextMenu = w.ListLabelMenus[i]
+					return true, nil
+				}
+			}
 		}
 	}
 	return false, nil
@@ -1005,96 +1282,94 @@ func (w *ListMilestoneMenu) Draw(x, y, x1, y1 int) {
 	if w.Focus != w.List {
 		return
 	}
-	printLine("milestone: [1] current [2] next [3] someday", x+2, y)
+
+	menu := "milestone:"
+	for i, tier := range w.Config.MilestoneTiers {
+		menu += fmt.Sprintf(" [%s] %s", tier.Hotkey, tier.Name)
+		if w.missingTier(i) {
+			menu += " (missing)"
+		}
+	}
+	printLine(menu, x+2, y)
 }
 
-// HandleEvent sets the milestone
-func (w *ListMilestoneMenu) HandleEvent(ev termbox.Event) (bool, error) {
-	issue := w.currentIssues[w.currentIndex]
-	milestones := w.Milestones[issue.Project]
-	if milestones == nil {
-		// TODO(termie): display error/warning
-		logger.Warnln("Couldn't find milestones for:", issue.Project)
-		w.Alert = fmt.Sprintf("Couldn't find valid milestones for: %s", issue.Project)
-		w.Focus = w.AlertModal
-		return false, nil
+// missingTier checks whether any of the issues the menu applies to is in a
+// project without a milestone for the tier
+func (w *ListMilestoneMenu) missingTier(tier int) bool {
+	for _, issue := range w.targets() {
+		milestones := w.Milestones[issue.Project]
+		if tier >= len(milestones) || milestones[tier] == nil {
+			return true
+		}
 	}
+	return false
+}
 
-	var milestone *Milestone
-	var index int
-	switch ev.Ch {
-	case '1':
-		// set current milestone
-		index = 1
-		milestone = milestones[0]
-	case '2':
-		// set next milestone
-		index = 2
-		milestone = milestones[1]
-	case '3':
-		// set someday milestone
-		index = 3
-		milestone = milestones[2]
-	default:
+// HandleEvent sets the milestone on the marked issues, or the current one
+func (w *ListMilestoneMenu) HandleEvent(ev termbox.Event) (bool, error) {
+	tier := -1
+	for i, t := range w.Config.MilestoneTiers {
+		if ev.Ch != 0 && ev.Ch == t.Key() {
+			tier = i
+		}
+	}
+	if tier < 0 {
 		return false, nil
 	}
+	name := w.Config.MilestoneTiers[tier].Name
+	index := w.Config.MilestoneTiers[tier].Index
 
+	w.batch(fmt.Sprintf("milestone %s", name), w.targets(), func(issue *Issue) error {
+		milestones := w.Milestones[issue.Project]
+		if tier >= len(milestones) || milestones[tier] == nil {
+			logger.Warnln("Couldn't find milestones for:", issue.Project)
+			return fmt.Errorf("No %s milestone for: %s", name, issue.Project)
+		}
+		milestone := milestones[tier]
 
-	_, _, err := w.Client.Issues.Edit(issue.Owner, issue.Repo, issue.Number, &github.IssueRequest{Milestone: &milestone.Number})
-	if err != nil {
-		return true, err
-	}
-
-	issue.Milestone = &IssueMilestone{Index: index, Milestone: milestone}
+		err := w.API.SetMilestone(issue, milestone)
+		if err != nil {
+			return err
+		}
+		issue.Milestone = &IssueMilestone{Index: index, Milestone: milestone}
+		return nil
+	})
 	return true, nil
-
 }
 
-// ListPriorityMenu for setting priority
-type ListPriorityMenu struct {
+// ListLabelMenu for setting the label of one of the dimensions
+type ListLabelMenu struct {
 	*ListWindow
+	index int
 }
 
-// NewListPriorityMenu ctor
-func NewListPriorityMenu(w *ListWindow) *ListPriorityMenu {
-	return &ListPriorityMenu{w}
+// NewListLabelMenu ctor
+func NewListLabelMenu(w *ListWindow, index int) *ListLabelMenu {
+	return &ListLabelMenu{w, index}
 }
 
 // Init noop (needed to prevent IssueList.Init being called)
-func (w *ListPriorityMenu) Init() error {
+func (w *ListLabelMenu) Init() error {
 	return nil
 }
 
-// Draw the priority menu
-func (w *ListPriorityMenu) Draw(x, y, x1, y1 int) {
+// Draw the label menu
+func (w *ListLabelMenu) Draw(x, y, x1, y1 int) {
 	if w.Focus != w.List {
 		return
 	}
 
-	menu := "priority:"
-	for i, p := range w.Priorities {
-		menu += fmt.Sprintf(" [%d] %s", i+1, p.Name)
+	d := w.Dimensions[w.index]
+	menu := fmt.Sprintf("%s:", d.Name)
+	for i, l := range d.Labels {
+		menu += fmt.Sprintf(" [%d] %s", i+1, l.Name)
 	}
 	printLine(menu, x+2, y)
 }
 
-// HandleEvent sets the priority
-func (w *ListPriorityMenu) HandleEvent(ev termbox.Event) (bool, error) {
-	issue := w.currentIssues[w.currentIndex]
-	labels := []string{}
-
-	// filter out any label that means a priority
-	for _, label := range issue.Labels {
-		found := false
-		for _, ours := range w.Priorities {
-			if label == ours.Name {
-				found = true
-			}
-		}
-		if !found {
-			labels = append(labels, label)
-		}
-	}
+// HandleEvent sets the label on the marked issues, or the current one
+func (w *ListLabelMenu) HandleEvent(ev termbox.Event) (bool, error) {
+	d := w.Dimensions[w.index]
 
 	// now attempt to grab our label via the index keyed in
 	i, err := strconv.Atoi(fmt.Sprintf("%c", ev.Ch))
@@ -1103,102 +1378,67 @@ func (w *ListPriorityMenu) HandleEvent(ev termbox.Event) (bool, error) {
 		return false, nil
 	}
 
-	if i > len(w.Priorities) {
+	if i > len(d.Labels) {
 		// TODO(termie): warning
 		return false, nil
 	}
-	var issuePriority IssuePriority
-	// a "0" will delete the label
+
+	what := fmt.Sprintf("%s none", d.Name)
 	if i > 0 {
-		pri := w.Priorities[i-1]
-		issuePriority = IssuePriority{Index: i, Priority: &pri}
-		labels = append(labels, pri.Name)
-	} else {
-		issuePriority = IssuePriority{Index: 0}
+		what = fmt.Sprintf("%s %s", d.Name, d.Labels[i-1].Name)
 	}
+	w.batch(what, w.targets(), func(issue *Issue) error {
+		label := ""
+		// a "0" will delete the label
+		if i > 0 {
+			label = labelFor(issue.Labels, d.Labels[i-1], w.Config.ShouldNormalizeAliases())
+		}
 
-	_, _, err = w.Client.Issues.ReplaceLabelsForIssue(issue.Owner, issue.Repo, issue.Number, labels)
-	if err != nil {
-		return true, err
-	}
-	issue.Priority = &issuePriority
-	issue.Labels = labels
+		labels := swapLabel(issue.Labels, d.Labels, label)
+		err := w.API.ReplaceLabels(issue, labels)
+		if err != nil {
+			return err
+		}
+		issue.Dimensions[w.index] = newIssueLabel(d, i)
+		issue.Labels = labels
+		return nil
+	})
 	return true, nil
 }
 
-// ListTypeMenu for setting type
-type ListTypeMenu struct {
-	*ListWindow
-}
-
-// NewListTypeMenu ctor
-func NewListTypeMenu(w *ListWindow) *ListTypeMenu {
-	return &ListTypeMenu{w}
-}
-
-// Init noop (needed to prevent IssueList.Init being called)
-func (w *ListTypeMenu) Init() error {
-	return nil
-}
-
-// Draw the type menu
-func (w *ListTypeMenu) Draw(x, y, x1, y1 int) {
-	if w.Focus != w.List {
-		return
-	}
-	menu := "type:"
-	for i, p := range w.Types {
-		menu += fmt.Sprintf(" [%d] %s", i+1, p.Name)
-	}
-	printLine(menu, x+2, y)
-}
-
-// HandleEvent sets the type
-func (w *ListTypeMenu) HandleEvent(ev termbox.Event) (bool, error) {
-	issue := w.currentIssues[w.currentIndex]
-	labels := []string{}
-
-	// filter out any label that means a priority
-	for _, label := range issue.Labels {
+// swapLabel filters out any of ours (or their aliases) from the labels and
+// adds label in their place, an empty label just removes them
+func swapLabel(labels []string, ours []Label, label string) []string {
+	out := []string{}
+	for _, l := range labels {
 		found := false
-		for _, ours := range w.Types {
-			if label == ours.Name {
+		for _, o := range ours {
+			if o.Matches(l) {
 				found = true
 			}
 		}
 		if !found {
-			labels = append(labels, label)
+			out = append(out, l)
 		}
 	}
-
-	// now attempt to grab our label via the index keyed in
-	i, err := strconv.Atoi(fmt.Sprintf("%c", ev.Ch))
-	if err != nil {
-		// TODO(termie): warning
-		return false, nil
+	if label != "" {
+		out = append(out, label)
 	}
+	return out
+}
 
-	if i > len(w.Types) {
-		// TODO(termie): warning
-		return false, nil
-	}
-	var issueType IssueType
-	// a "0" will delete the label
-	if i > 0 {
-		pri := w.Types[i-1]
-		issueType = IssueType{Index: i, Type: &pri}
-		labels = append(labels, pri.Name)
-	} else {
-		issueType = IssueType{Index: 0}
+// labelFor is the name to give label on an issue, if the issue already
+// has it under an alias that's kept unless we're normalizing
+func labelFor(labels []string, label Label, normalize bool) string {
+	if normalize {
+		return label.Name
 	}
-
-	_, _, err = w.Client.Issues.ReplaceLabelsForIssue(issue.Owner, issue.Repo, issue.Number, labels)
-	if err != nil {
-		return true, err
+	for _, l := range labels {
+		if label.Matches(l) {
+			return l
+		}
 	}
-	issue.Type = &issueType
-	issue.Labels = labels
-	return true, nil
+	return label.Name
 }
 
 // Issue List
@@ -1210,7 +1450,19 @@ type ListWindow struct {
 	currentIndex  int
 	lastIndex     int
 	scrollIndex   int
-	expanding     bool
+	// marked issues by url, the menus apply to all of them
+	marked map[string]bool
+	// edits from the menus that can be undone, and redone after that
+	undos []*Edit
+	redos []*Edit
+	// notice is what just happened, for the status line
+	notice string
+	// draft is the comment being written in the ComposeWindow
+	draft *Draft
+	// assigning is the AssignWindow's search
+	assigning *Assigning
+	// collaborators by project, fetched the first time they're needed
+	collaborators map[string][]string
 
 	currentFilter string
 
@@ -1219,7 +1471,7 @@ type ListWindow struct {
 
 // NewListWindow ctor
 func NewListWindow(w *TopIssueWindow) *ListWindow {
-	return &ListWindow{Subwindow: &Subwindow{w}}
+	return &ListWindow{Subwindow: &Subwindow{w}, marked: map[string]bool{}, collaborators: map[string][]string{}}
 }
 
 // Init fetches the initial issues
@@ -1230,7 +1482,11 @@ func (w *ListWindow) Init() error {
 	// }
 
 	// fetch the initial list of issues, etc
-	go w.refresh()
+	w.loading.Add(1)
+	go func() {
+		defer w.loading.Done()
+		w.refresh()
+	}()
 
 	return nil
 }
@@ -1249,13 +1505,13 @@ func (w *ListWindow) Draw(x, y, x1, y1 int) {
 
 	// headers
 	headerFg := termbox.ColorDefault | termbox.AttrUnderline
-	headers := " idx repo  num  title"
+	headers := fmt.Sprintf(" %-*s who repo  num  title", w.idxColumn(), "idx")
 	for i, c := range headers {
 		fg := headerFg
 		if c == ' ' {
 			fg = termbox.ColorDefault
 		}
-		termbox.SetCell(x+1+i, y+line, c, fg, termbox.ColorDefault)
+		screen.SetCell(x+1+i, y+line, c, fg, termbox.ColorDefault)
 	}
 
 	line++
@@ -1263,7 +1519,14 @@ func (w *ListWindow) Draw(x, y, x1, y1 int) {
 	if w.scrollIndex > 0 {
 		// // printLine("--more--", x+3, y)
 		// printLine(string('\u2191'), x, y)
-		termbox.SetCell(x, y+line, '\u2191', termbox.ColorDefault, termbox.ColorDefault)
+		screen.SetCell(x, y+line, '\u2191', termbox.ColorDefault, termbox.ColorDefault)
+	}
+
+	if marked := len(w.markedIssues()); marked > 0 {
+		w.Status += fmt.Sprintf("%d marked ", marked)
+	}
+	if w.notice != "" {
+		w.Status += fmt.Sprintf("%s ", w.notice)
 	}
 
 	for i, issue := range w.currentIssues {
@@ -1271,6 +1534,9 @@ func (w *ListWindow) Draw(x, y, x1, y1 int) {
 			continue
 		}
 		cursor := " "
+		if w.marked[issue.URL] {
+			cursor = "*"
+		}
 		if i == w.currentIndex && w.Focus == w {
 			cursor = ">"
 			w.Status += fmt.Sprintf("%s/%s", issue.Owner, issue.Repo)
@@ -1285,37 +1551,30 @@ func (w *ListWindow) Draw(x, y, x1, y1 int) {
 			repo = repo[:5]
 		}
 
-		printLine(fmt.Sprintf(
-			"%s%d%d%d % 5s/%-4d %s",
+		fg := termbox.ColorDefault
+		if w.marked[issue.URL] {
+			fg |= termbox.AttrBold
+		}
+		printLineColor(fmt.Sprintf(
+			"%s%-*s %-3s % 5s/%-4d %s",
 			cursor,
-			issue.Milestone.Index,
-			issue.Priority.Index,
-			issue.Type.Index,
+			w.idxColumn(),
+			issue.Idx(),
+			initials(issue.Assignees, 3),
 			repo,
 			issue.Number,
 			issue.Title,
-		), x+1, y+line)
+		), x+1, y+line, fg, termbox.ColorDefault)
 
 		// we've reached the edge
 		if y+line >= y1 {
 			if i < len(w.currentIssues) {
-				termbox.SetCell(x, y1, '\u2193', termbox.ColorDefault, termbox.ColorDefault)
+				screen.SetCell(x, y1, '\u2193', termbox.ColorDefault, termbox.ColorDefault)
 				// printLine("--more--", x+3, y1-1)
 			}
 			break
 		}
 
-		// Check for expanded
-		if i == w.currentIndex && w.expanding {
-			y++
-			printLine(issue.URL, x+5, y+line)
-			body := wordWrap(issue.Body, x1-9)
-			for _, text := range body {
-				y++
-				printLine(text, x+5, y+line)
-			}
-		}
-
 		line++
 	}
 }
@@ -1350,6 +1609,15 @@ func (w *ListWindow) HandleEvent(ev termbox.Event) (bool, error) {
 				return true, nil
 			}
 			return false, nil
+		case termbox.KeySpace:
+			if len(w.currentIssues) > 0 {
+				issue := w.currentIssues[w.currentIndex]
+				w.setMarked(issue, !w.marked[issue.URL])
+			}
+			return true, nil
+		case termbox.KeyCtrlR:
+			w.redo()
+			return true, nil
 		case termbox.KeyPgdn:
 			w.scroll(10)
 			return true, nil
@@ -1386,50 +1654,164 @@ func (w *ListWindow) HandleEvent(ev termbox.Event) (bool, error) {
 				w.scroll(-10)
 			}
 			return true, nil
+		default:
+			switch ev.Ch {
+			case '*':
+				w.markAll()
+				return true, nil
+			case 'u':
+				w.undo()
+				return true, nil
+			}
 		}
 	}
 
 	return false, nil
 }
 
-// refresh updates all the issues for the current query
+// setMarked marks or unmarks an issue
+func (w *ListWindow) setMarked(issue *Issue, marked bool) {
+	if marked {
+		w.marked[issue.URL] = true
+	} else {
+		delete(w.marked, issue.URL)
+	}
+}
+
+// markAll marks every issue the filter shows, or unmarks them if they
+// already are
+func (w *ListWindow) markAll() {
+	marked := len(w.markedIssues()) < len(w.currentIssues)
+	for _, issue := range w.currentIssues {
+		w.setMarked(issue, marked)
+	}
+}
+
+// markedIssues are the marked issues the filter shows
+func (w *ListWindow) markedIssues() []*Issue {
+	issues := []*Issue{}
+	for _, issue := range w.currentIssues {
+		if w.marked[issue.URL] {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// targets are the issues a menu applies to, the marked ones or else the
+// current one
+func (w *ListWindow) targets() []*Issue {
+	if issues := w.markedIssues(); len(issues) > 0 {
+		return issues
+	}
+	if len(w.currentIssues) == 0 {
+		return nil
+	}
+	return []*Issue{w.currentIssues[w.currentIndex]}
+}
+
+// batch applies a change to each issue and keeps what they were before
+// so it can be undone
+func (w *ListWindow) batch(what string, issues []*Issue, apply func(*Issue) error) {
+	edit := &Edit{What: what}
+	w.each(fmt.Sprintf("Setting %s", what), issues, func(issue *Issue) error {
+		before := stateOf(issue)
+		if err := apply(issue); err != nil {
+			return err
+		}
+		edit.Changes = append(edit.Changes, &IssueChange{Issue: issue, Before: before, After: stateOf(issue)})
+		return nil
+	})
+	w.pushEdit(edit)
+}
+
+// each runs do on each issue, showing progress as it goes and carrying on
+// past failures, which are listed at the end and returned
+func (w *ListWindow) each(doing string, issues []*Issue, do func(*Issue) error) []*Issue {
+	failed := []*Issue{}
+	failures := []string{}
+	for i, issue := range issues {
+		if len(issues) > 1 {
+			w.Alert = fmt.Sprintf("%s: %d of %d", doing, i+1, len(issues))
+			w.Redraw()
+		}
+		if err := do(issue); err != nil {
+			logger.Errorln(doing, "failed for", issue.URL, err)
+			failed = append(failed, issue)
+			failures = append(failures, fmt.Sprintf("%s/%s#%d: %s", issue.Owner, issue.Repo, issue.Number, err))
+		}
+	}
+	w.Alert = ""
+	if len(failures) == 0 {
+		return failed
+	}
+
+	// don't let a lot of failures run off the screen
+	const maxFailures = 10
+	if more := len(failures) - maxFailures; more > 0 {
+		failures = append(failures[:maxFailures], fmt.Sprintf("... and %d more", more))
+	}
+	w.Alert = fmt.Sprintf("%s failed for %d of %d issues:\n%s", doing, len(failed), len(issues), strings.Join(failures, "\n"))
+	w.Focus = w.AlertModal
+	return failed
+}
+
+// refresh updates all the issues for the current query, if we have them
+// cached they're shown right away and only the changes are fetched
 func (w *ListWindow) refresh() error {
 	defer profile("ListWindow.refresh").Stop()
 
-	// Decide what to search for
-	// 1. if org is specified, use that
-	// 2. if target is specified, use that
-	// 3. if no target is specified but projects are configued, use that
-	// 4. if no target and no projects, list by user
-	var resultsChan <-chan *IssueResult
-	var err error
-	if w.Org != "" {
-		resultsChan = w.API.ByOrg(w.Org)
-	} else if w.Target != "" {
-		resultsChan = w.API.Search(w.Target)
-	} else {
-		resultsChan = w.API.ByUser()
+	// offline we only have the cache, plus what we've changed since
+	if w.Journal != nil {
+		w.setIssues(w.newIssues(w.Journal.Apply(w.Cache.Issues), nil))
+		w.Redraw()
+		return nil
 	}
-	if err != nil {
-		return err
+
+	since := w.Cache.LastSync
+	if len(w.Cache.Issues) > 0 {
+		w.setIssues(w.newIssues(w.Cache.Issues, nil))
+		w.Syncing = true
+		w.Redraw()
+		defer func() { w.Syncing = false }()
+	}
+	// if our milestones came from the cache, Current may have moved on
+	if w.Cache.Milestones != nil {
+		w.Milestones = w.fetchMilestones()
 	}
+	started := time.Now()
 
-	issues := []*Issue{}
-	w.Alert = "Fetching issues..."
-	w.Redraw()
+	resultsChan := fetchResults(w.API, w.Org, w.Target, since)
+
+	fetched := []github.Issue{}
+	if since.IsZero() {
+		w.Alert = "Fetching issues..."
+		w.Redraw()
+	}
 	for result := range resultsChan {
 		if result.Err != nil {
+			w.Alert = fmt.Sprintf("Error fetching issues: %s", result.Err)
+			w.Redraw()
 			return result.Err
 		}
-		for _, issue := range result.Issues {
-			issues = append(issues, NewIssue(issue, w.Milestones, w.Priorities, w.Types))
+		fetched = append(fetched, result.Issues...)
+		if since.IsZero() {
+			w.setIssues(w.newIssues(fetched, nil))
+			w.Alert = fmt.Sprintf("Fetching issues, got: %d", len(fetched))
+			w.Redraw()
 		}
-		w.issues = issues
-		w.currentIssues = issues
-		w.Alert = fmt.Sprintf("Fetching issues, got: %d", len(issues))
-		w.Redraw()
 	}
 
+	w.Cache.Merge(fetched)
+	w.Cache.Milestones = w.Milestones
+	w.Cache.LastSync = started
+	if err := w.Cache.Save(); err != nil {
+		logger.Errorln("Couldn't save cache:", err)
+	}
+
+	issues := w.newIssues(w.Cache.Issues, fetched)
+	w.setIssues(issues)
+
 	if w.Opts.Debug {
 		data, err := json.MarshalIndent(issues, "", "  ")
 		if err != nil {
@@ -1441,10 +1823,79 @@ func (w *ListWindow) refresh() error {
 		}
 	}
 	w.Alert = ""
+	w.Syncing = false
 	w.Redraw()
 	return nil
 }
 
+// newIssues builds our Issues against the current milestones, keeping
+// what we already have of the ones that weren't just fetched, so changes
+// made while syncing aren't lost
+func (w *ListWindow) newIssues(issues []github.Issue, fetched []github.Issue) []*Issue {
+	keep := map[string]*Issue{}
+	if fetched != nil {
+		for _, issue := range w.issues {
+			keep[issue.URL] = issue
+		}
+		for _, issue := range fetched {
+			delete(keep, *issue.HTMLURL)
+		}
+	}
+
+	out := []*Issue{}
+	for _, issue := range issues {
+		if ours, ok := keep[*issue.HTMLURL]; ok {
+			// the milestones may have changed since, so build it again
+			issue = withLocalState(issue, ours)
+		}
+		out = append(out, NewIssue(issue, w.Milestones, w.Config.MilestoneTiers, w.Dimensions))
+	}
+	return out
+}
+
+// withLocalState is a copy of issue with the milestone, labels and
+// assignees we have for it locally
+func withLocalState(issue github.Issue, ours *Issue) github.Issue {
+	issue.Milestone = nil
+	if m := ours.Milestone; m != nil && m.Milestone != nil {
+		issue.Milestone = &github.Milestone{
+			Number: github.Int(m.Number),
+			Title:  github.String(m.Title),
+			DueOn:  m.DueOn,
+		}
+	}
+	issue.Labels = nil
+	for _, label := range ours.Labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: github.String(label)})
+	}
+	issue.Assignees = nil
+	issue.Assignee = nil
+	for _, login := range ours.Assignees {
+		issue.Assignees = append(issue.Assignees, &github.User{Login: github.String(login)})
+	}
+	if len(issue.Assignees) > 0 {
+		issue.Assignee = issue.Assignees[0]
+	}
+	// so a comment posted while syncing still gets fetched
+	if !ours.UpdatedAt.IsZero() {
+		updated := ours.UpdatedAt
+		issue.UpdatedAt = &updated
+	}
+	return issue
+}
+
+// setIssues replaces the issues, keeping the current filter
+func (w *ListWindow) setIssues(issues []*Issue) {
+	w.issues = issues
+	w.currentIssues = filterIssues(issues, w.currentFilter)
+	if w.currentIndex >= len(w.currentIssues) {
+		w.currentIndex = len(w.currentIssues) - 1
+	}
+	if w.currentIndex < 0 {
+		w.currentIndex = 0
+	}
+}
+
 // filter the issues based on substring
 func (w *ListWindow) filter(substr string) {
 	if substr == w.currentFilter {
@@ -1453,9 +1904,13 @@ func (w *ListWindow) filter(substr string) {
 	w.currentFilter = substr
 	w.scrollIndex = 0
 	w.currentIndex = 0
+	w.currentIssues = filterIssues(w.issues, substr)
+}
+
+// filterIssues returns the issues matching every word of the filter
+func filterIssues(issues []*Issue, substr string) []*Issue {
 	if substr == "" {
-		w.currentIssues = w.issues
-		return
+		return issues
 	}
 
 	parts := strings.Split(substr, " ")
@@ -1463,11 +1918,17 @@ func (w *ListWindow) filter(substr string) {
 	selected := []*Issue{}
 
 IssueLoop:
-	for _, issue := range w.issues {
-		haystack := fmt.Sprintf("%d %s %s m%d p%d t%d", issue.Number, issue.Repo, issue.Title, issue.Milestone.Index, issue.Priority.Index, issue.Type.Index)
+	for _, issue := range issues {
+		haystack := fmt.Sprintf("%d %s %s m%d", issue.Number, issue.Repo, issue.Title, issue.Milestone.Index)
+		for _, l := range issue.Dimensions {
+			haystack += fmt.Sprintf(" %s%d", l.Hotkey, l.Index)
+		}
 		for _, label := range issue.Labels {
 			haystack += fmt.Sprintf(" %s", label)
 		}
+		for _, login := range issue.Assignees {
+			haystack += fmt.Sprintf(" @%s", login)
+		}
 		haystack = strings.ToLower(haystack)
 
 		for _, search := range parts {
@@ -1483,7 +1944,7 @@ IssueLoop:
 		// if we got here we matched
 		selected = append(selected, issue)
 	}
-	w.currentIssues = selected
+	return selected
 }
 
 // sort the issues based on sort string
@@ -1491,7 +1952,7 @@ func (w *ListWindow) sort() {
 	if w.SortFunc == nil {
 		return
 	}
-	sort.Sort(w)
+	sort.Sort(&issueSorter{w.currentIssues, w.SortFunc, w.SortAsc})
 }
 
 // scroll moves the dang window contents around
@@ -1511,21 +1972,28 @@ func (w *ListWindow) scroll(i int) {
 	}
 }
 
+// issueSorter sorts issues for the ListWindow and the list command
+type issueSorter struct {
+	issues   []*Issue
+	sortFunc func(*Issue, *Issue) bool
+	asc      bool
+}
+
 // Len for Sortable
-func (w *ListWindow) Len() int {
-	return len(w.currentIssues)
+func (s *issueSorter) Len() int {
+	return len(s.issues)
 }
 
 // Swap for Sortable
-func (w *ListWindow) Swap(i, j int) {
-	(w.currentIssues)[i], (w.currentIssues)[j] = (w.currentIssues)[j], (w.currentIssues)[i]
+func (s *issueSorter) Swap(i, j int) {
+	s.issues[i], s.issues[j] = s.issues[j], s.issues[i]
 }
 
-// Less for Sortable, defers to TriageSortLess
-func (w *ListWindow) Less(i, j int) bool {
-	result := w.SortFunc(w.currentIssues[i], w.currentIssues[j])
-	if w.SortAsc == false {
-		return !result
+// Less for Sortable, a descending sort swaps the sides rather than
+// negating, so that equal issues aren't less than each other
+func (s *issueSorter) Less(i, j int) bool {
+	if !s.asc {
+		return s.sortFunc(s.issues[j], s.issues[i])
 	}
-	return result
+	return s.sortFunc(s.issues[i], s.issues[j])
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codegangsta/cli"
+)
+
+var update = flag.Bool("update", false, "write the golden files instead of checking them")
+
+// snapshotTests are keys to press in the ui and the golden file holding
+// what should be on the screen afterwards, and the calls made to get there
+var snapshotTests = []struct {
+	golden string
+	keys   string
+}{
+	{"list", ""},
+	{"help", "?"},
+	{"move", "<down><down><up>"},
+	{"filter", "/bar<enter>"},
+	{"filter-assignee", "/@alice<enter>"},
+	{"sort", "s-num<enter>"},
+	{"milestone-menu", "m"},
+	{"set-milestone", "<down><down>m3"},
+	{"missing-milestone", "m1"},
+	{"set-priority", "<down>p1"},
+	{"mark", "<space><down><space>t2"},
+	{"mark-all", "*p4"},
+	{"undo", "<down>t2p1uu"},
+	{"redo", "<down>p1u<c-r>"},
+	{"detail", "<enter>"},
+	{"assign-menu", "a"},
+	{"assign-search", "ajd<enter>"},
+	{"comment-menu", "c"},
+	{"compose", "cchello<enter>there<c-s>"},
+	{"reply", "c1"},
+	{"reply-marked", "<space><down><space>c1"},
+	{"detail-reply", "<enter>1"},
+}
+
+// testOptions are empty options, as if no flags were given
+func testOptions() *Options {
+	return &Options{CLI: cli.NewContext(cli.NewApp(), flag.NewFlagSet("test", flag.ContinueOnError), nil)}
+}
+
+// testConfig is the default config for the projects in the fixture,
+// without reading any config files
+func testConfig() *Config {
+	config := &Config{
+		Projects:         Projects{"wercker/foo", "wercker/bar"},
+		Priorities:       DefaultPriorities,
+		Types:            DefaultTypes,
+		NextMilestone:    DefaultNextMilestone,
+		SomedayMilestone: DefaultSomedayMilestone,
+		Replies:          []Reply{{Name: "logs", Hotkey: "1", Body: "Could you attach the logs?"}},
+	}
+	config.setDimensions()
+	config.setMilestoneTiers()
+	return config
+}
+
+func TestSnapshots(t *testing.T) {
+	// comments are written in the ComposeWindow, not an editor
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Setenv(env, "")
+	}
+
+	for _, test := range snapshotTests {
+		events, err := parseKeys(test.keys)
+		if err != nil {
+			t.Fatalf("%s: %s", test.golden, err)
+		}
+		api, err := LoadFakeAPI(filepath.Join("testdata", "raw_issues.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := snapshot(testOptions(), testConfig(), api, "", events, 100, 16)
+		if err != nil {
+			t.Errorf("%s: %s", test.golden, err)
+			continue
+		}
+
+		golden := filepath.Join("testdata", test.golden+".golden")
+		if *update {
+			err := ioutil.WriteFile(golden, []byte(out), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Errorf("%s: %s, run go test -update to write it", test.golden, err)
+			continue
+		}
+		if diff := lineDiff(string(expected), out); diff != "" {
+			t.Errorf("%s (%q) doesn't match %s:\n%s", test.golden, test.keys, golden, diff)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	events, err := parseKeys("a<down><<<tab> ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 5 || events[0].Ch != 'a' || events[1].Key != namedKeys["down"] || events[2].Ch != '<' || events[3].Key != namedKeys["tab"] || events[4].Key != namedKeys["space"] {
+		t.Errorf("wrong events: %+v", events)
+	}
+	for _, bad := range []string{"<down", "<nope>"} {
+		if _, err := parseKeys(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestResolveTarget(t *testing.T) {
+	config := testConfig()
+	config.Projects = Projects{"wercker/foo", "github.com/wercker/bar", "ghe.corp/team/repo"}
+	config.Gitlab.Projects = Projects{"group/sub/repo"}
+
+	tests := []struct {
+		org, target string
+		search      string
+	}{
+		{"", "", "is:open is:issue repo:wercker/foo repo:wercker/bar repo:ghe.corp/team/repo repo:group/sub/repo"},
+		{"", "label:bug", "is:open is:issue label:bug"},
+		{"wercker", "", ""},
+	}
+	for _, test := range tests {
+		org, search := resolveTarget(config, test.org, test.target)
+		if org != test.org || search != test.search {
+			t.Errorf("%q %q: got %q %q, expected %q", test.org, test.target, org, search, test.search)
+		}
+	}
+
+	// github-url moves the default host, and github.com is just another
+	config.GithubURL = "https://ghe.corp/api/v3/"
+	_, search := resolveTarget(config, "", "")
+	expected := "is:open is:issue repo:wercker/foo repo:github.com/wercker/bar repo:team/repo repo:group/sub/repo"
+	if search != expected {
+		t.Errorf("got %q, expected %q", search, expected)
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v2"
@@ -40,33 +42,41 @@ var (
 				os.Exit(1)
 			}
 			project := c.Args().First()
-			err = cmdSetLabels(opts, project)
+			err = cmdSetLabels(opts, project, c.Bool("dry-run"), c.Bool("diff"), c.Bool("migrate"), c.Bool("prune"))
 			if err != nil {
 				SoftExit(opts, err)
 			}
 		},
+		Flags: []cli.Flag{
+			cli.BoolFlag{Name: "dry-run", Usage: "show what would change without changing it"},
+			cli.BoolFlag{Name: "diff", Usage: "show what would change as a diff, without changing it"},
+			cli.BoolFlag{Name: "migrate", Usage: "rename labels listed under renames in the config"},
+			cli.BoolFlag{Name: "prune", Usage: "delete labels that aren't in the config or keep-labels"},
+		},
 	}
 )
 
 // cmdShowLabels prints the labels for a project for easy inclusion
 // in the config
 func cmdShowLabels(opts *Options, project string) error {
-	tc := AuthClient(opts)
-	client := github.NewClient(tc)
+	config, err := LoadConfig(opts)
+	if err != nil {
+		return err
+	}
 
-	owner, repo, err := ownerRepo(project)
+	client, owner, repo, err := NewGithubClients(opts, config).For(project)
 	if err != nil {
 		return err
 	}
 
-	labels, _, err := client.Issues.ListLabels(owner, repo, &github.ListOptions{})
+	labels, err := listLabels(client, owner, repo)
 	if err != nil {
 		return err
 	}
 
 	out := []Label{}
 	for _, label := range labels {
-		out = append(out, Label{*label.Name, *label.Color})
+		out = append(out, Label{Name: label.Name, Color: label.Color, Description: label.description()})
 	}
 
 	d, err := yaml.Marshal(out)
@@ -77,26 +87,16 @@ func cmdShowLabels(opts *Options, project string) error {
 	return nil
 }
 
-func cmdSetLabels(opts *Options, target string) error {
-	tc := AuthClient(opts)
-	client := github.NewClient(tc)
+// cmdSetLabels makes the labels in target projects match our config, or
+// just shows what it would do
+func cmdSetLabels(opts *Options, target string, dryRun, diff, migrate, prune bool) error {
 	config, err := LoadConfig(opts)
 	if err != nil {
 		return err
 	}
+	clients := NewGithubClients(opts, config)
 
-	ourLabels := []Label{}
-	for _, p := range config.Priorities {
-		ourLabels = append(ourLabels, Label(p))
-	}
-	for _, t := range config.Types {
-		ourLabels = append(ourLabels, Label(t))
-	}
-
-	ourLabelsMap := map[string]string{}
-	for _, label := range ourLabels {
-		ourLabelsMap[label.Name] = label.Color
-	}
+	ourLabels := config.Labels()
 
 	var projects []string
 	if target == "all" {
@@ -108,44 +108,256 @@ func cmdSetLabels(opts *Options, target string) error {
 	for _, project := range projects {
 		logger.Debugln("Setting labels for:", project)
 
-		owner, repo, err := ownerRepo(project)
+		client, owner, repo, err := clients.For(project)
 		if err != nil {
 			return err
 		}
 
-		theirLabels, _, err := client.Issues.ListLabels(owner, repo, &github.ListOptions{})
+		plan, err := planLabels(client, config, project, owner, repo, ourLabels, migrate, prune)
 		if err != nil {
 			return err
 		}
-		theirLabelsMap := map[string]github.Label{}
-		for _, label := range theirLabels {
-			theirLabelsMap[*label.Name] = label
+		if dryRun || diff {
+			plan.Print(diff)
+			continue
 		}
+		err = plan.Apply()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		for _, ours := range ourLabels {
-			theirs, ok := theirLabelsMap[ours.Name]
-			// check if we already exist but don't have the same color
-			if ok && *theirs.Color != ours.Color {
-				*theirs.Color = ours.Color
-				logger.Debugln("  updating color:", ours.Name)
-				_, _, err = client.Issues.EditLabel(owner, repo, ours.Name, &theirs)
-				if err != nil {
-					return err
-				}
-			} else if !ok {
-				logger.Debugln("  creating:", ours.Name)
-				_, _, err = client.Issues.CreateLabel(
-					owner,
-					repo,
-					&github.Label{Name: &ours.Name, Color: &ours.Color},
-				)
-				if err != nil {
-					return err
-				}
-			} else {
-				logger.Debugln("  found existing:", ours.Name)
+// labelsPreview gets us label descriptions, which our go-github doesn't
+// know about yet
+const labelsPreview = "application/vnd.github.symmetra-preview+json"
+
+// githubLabel is a github.Label with a description
+type githubLabel struct {
+	Name        string  `json:"name"`
+	Color       string  `json:"color"`
+	Description *string `json:"description,omitempty"`
+	// NewName is how the preview api renames a label
+	NewName string `json:"new_name,omitempty"`
+}
+
+// description of a label, empty if it doesn't have one
+func (l *githubLabel) description() string {
+	if l.Description == nil {
+		return ""
+	}
+	return *l.Description
+}
+
+// labelsRequest does a request against the labels api with the preview
+// header, decoding the response into v if it isn't nil
+func labelsRequest(client *github.Client, method, path string, body interface{}, v interface{}) (*github.Response, error) {
+	req, err := client.NewRequest(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", labelsPreview)
+	return client.Do(req, v)
+}
+
+// listLabels gets all of a project's labels, descriptions included
+func listLabels(client *github.Client, owner, repo string) ([]*githubLabel, error) {
+	labels := []*githubLabel{}
+	page := 1
+	for {
+		path := fmt.Sprintf("repos/%s/%s/labels?per_page=100&page=%d", owner, repo, page)
+		batch := []*githubLabel{}
+		resp, err := labelsRequest(client, "GET", path, nil, &batch)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, batch...)
+		if resp.NextPage == 0 {
+			return labels, nil
+		}
+		page = resp.NextPage
+	}
+}
+
+// createLabel from one of ours
+func createLabel(client *github.Client, owner, repo string, label Label) error {
+	body := &githubLabel{Name: label.Name, Color: label.Color}
+	if label.Description != "" {
+		body.Description = &label.Description
+	}
+	path := fmt.Sprintf("repos/%s/%s/labels", owner, repo)
+	_, err := labelsRequest(client, "POST", path, body, nil)
+	return err
+}
+
+// editLabel makes the label called name look like one of ours, renaming it
+// if the names differ
+func editLabel(client *github.Client, owner, repo, name string, label Label) error {
+	body := &githubLabel{Name: label.Name, Color: label.Color}
+	if label.Description != "" {
+		body.Description = &label.Description
+	}
+	if name != label.Name {
+		body.NewName = label.Name
+	}
+	path := fmt.Sprintf("repos/%s/%s/labels/%s", owner, repo, escapeLabel(name))
+	_, err := labelsRequest(client, "PATCH", path, body, nil)
+	return err
+}
+
+// escapeLabel for the path of a label's url, like url.PathEscape (which is
+// too new for us) spaces are %20 and slashes are escaped too
+func escapeLabel(name string) string {
+	return strings.Replace(url.QueryEscape(name), "+", "%20", -1)
+}
+
+// labelSummary is how we show a label's color and description in a plan
+func labelSummary(color, description string) string {
+	if description == "" {
+		return color
+	}
+	return fmt.Sprintf("%s %q", color, description)
+}
+
+// keepLabel checks whether prune has to leave a label alone, deleting it
+// takes it off every issue so anything the config knows about stays: our
+// labels and their aliases, renames that haven't been migrated yet and the
+// ones it says to keep
+func keepLabel(config *Config, ourLabels []Label, name string) bool {
+	for _, label := range ourLabels {
+		if label.Matches(name) {
+			return true
+		}
+	}
+	for old := range config.Renames {
+		if strings.EqualFold(old, name) {
+			return true
+		}
+	}
+	for _, keep := range config.KeepLabels {
+		if strings.EqualFold(keep, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// planLabels works out what needs to change for a project to have our
+// labels, migrating renamed labels and pruning ones we don't know about if
+// asked to
+func planLabels(client *github.Client, config *Config, project, owner, repo string, ourLabels []Label, migrate, prune bool) (*Plan, error) {
+	theirLabels, err := listLabels(client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	theirLabelsMap := map[string]*githubLabel{}
+	for _, label := range theirLabels {
+		theirLabelsMap[label.Name] = label
+	}
+	ourLabelsMap := map[string]Label{}
+	for _, label := range ourLabels {
+		ourLabelsMap[label.Name] = label
+	}
+
+	plan := &Plan{Project: project}
+
+	// renaming keeps the label on all the issues that had it, so do it
+	// before we'd create the new one
+	if migrate {
+		olds := []string{}
+		for old := range config.Renames {
+			olds = append(olds, old)
+		}
+		sort.Strings(olds)
+		for _, old := range olds {
+			name := config.Renames[old]
+			ours, isOurs := ourLabelsMap[name]
+			_, hasOld := theirLabelsMap[old]
+			_, hasNew := theirLabelsMap[name]
+			if !isOurs || !hasOld {
+				continue
 			}
+			if hasNew {
+				logger.Warnf("Not renaming %s to %s in %s, both exist", old, name, project)
+				continue
+			}
+			old := old
+			plan.Add(&Change{
+				Action: "rename",
+				Kind:   "label",
+				Name:   old,
+				To:     name,
+				apply: func() error {
+					return editLabel(client, owner, repo, old, ours)
+				},
+			})
+			// from here on it's as if it was always called that
+			delete(theirLabelsMap, old)
+			theirLabelsMap[name] = &githubLabel{Name: ours.Name, Color: ours.Color, Description: &ours.Description}
 		}
 	}
-	return nil
+
+	for _, ours := range ourLabels {
+		ours := ours
+		theirs, ok := theirLabelsMap[ours.Name]
+		if !ok {
+			plan.Add(&Change{
+				Action: "create",
+				Kind:   "label",
+				Name:   ours.Name,
+				To:     labelSummary(ours.Color, ours.Description),
+				apply: func() error {
+					return createLabel(client, owner, repo, ours)
+				},
+			})
+			continue
+		}
+
+		// check if we already exist but don't look the same, we only
+		// touch descriptions if we have one
+		description := theirs.description()
+		if ours.Description != "" {
+			description = ours.Description
+		}
+		if theirs.Color == ours.Color && theirs.description() == description {
+			logger.Debugln("  found existing:", ours.Name)
+			continue
+		}
+		plan.Add(&Change{
+			Action: "update",
+			Kind:   "label",
+			Name:   ours.Name,
+			From:   labelSummary(theirs.Color, theirs.description()),
+			To:     labelSummary(ours.Color, description),
+			apply: func() error {
+				return editLabel(client, owner, repo, ours.Name, ours)
+			},
+		})
+	}
+
+	if prune {
+		names := []string{}
+		for name := range theirLabelsMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if keepLabel(config, ourLabels, name) {
+				continue
+			}
+			name := name
+			plan.Add(&Change{
+				Action: "delete",
+				Kind:   "label",
+				Name:   name,
+				From:   labelSummary(theirLabelsMap[name].Color, theirLabelsMap[name].description()),
+				apply: func() error {
+					_, err := client.Issues.DeleteLabel(owner, repo, name)
+					return err
+				},
+			})
+		}
+	}
+	return plan, nil
 }
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestEscapeLabel(t *testing.T) {
+	tests := map[string]string{
+		"bug":              "bug",
+		"good first issue": "good%20first%20issue",
+		"area/ui":          "area%2Fui",
+		"c++":              "c%2B%2B",
+		"100%":             "100%25",
+		"é":                "%C3%A9",
+	}
+	for name, expected := range tests {
+		if escaped := escapeLabel(name); escaped != expected {
+			t.Errorf("%q: got %q, expected %q", name, escaped, expected)
+		}
+	}
+}
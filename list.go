@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/google/go-github/github"
+)
+
+var (
+	listCommand = cli.Command{
+		Name:      "list",
+		Usage:     "list issues like the ui would, for scripts",
+		ArgsUsage: "[query]",
+		Action: func(c *cli.Context) {
+			opts, err := NewOptions(c)
+			if err != nil {
+				logger.Errorln("Invalid options", err)
+				os.Exit(1)
+			}
+			target := c.Args().First()
+			err = cmdList(opts, target, c.String("filter"), c.String("sort"), c.String("format"))
+			if err != nil {
+				SoftExit(opts, err)
+			}
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "org", Usage: "list by org"},
+			cli.StringFlag{Name: "filter", Usage: "filter like the ui's filter box, e.g. \"m1 p2 bug\""},
+			cli.StringFlag{Name: "sort", Value: "+idx", Usage: "sort like the ui's sort box: idx, repo, num or title, prefixed with + or -"},
+			cli.StringFlag{Name: "format", Value: "table", Usage: "table, json or csv"},
+			cli.StringFlag{Name: "fixture", Usage: "use issues from a raw_issues.json instead of github"},
+		},
+	}
+)
+
+// ListRow is what we print for each issue
+type ListRow struct {
+	Idx       string `json:"idx"`
+	Project   string `json:"project"`
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	Milestone string `json:"milestone"`
+	// Dimensions are the label the issue has for each dimension by name,
+	// empty if it has none
+	Dimensions map[string]string `json:"dimensions"`
+	Labels     []string          `json:"labels"`
+	Assignees  []string          `json:"assignees"`
+	URL        string            `json:"url"`
+}
+
+// NewListRow from an Issue
+func NewListRow(issue *Issue) *ListRow {
+	row := &ListRow{
+		Idx:        issue.Idx(),
+		Project:    issue.Project,
+		Number:     issue.Number,
+		Title:      issue.Title,
+		Dimensions: map[string]string{},
+		Labels:     issue.Labels,
+		Assignees:  issue.Assignees,
+		URL:        issue.URL,
+	}
+	if issue.Milestone.Milestone != nil {
+		row.Milestone = issue.Milestone.Title
+	}
+	for _, l := range issue.Dimensions {
+		row.Dimensions[l.Dimension] = ""
+		if l.Label != nil {
+			row.Dimensions[l.Dimension] = l.Name
+		}
+	}
+	return row
+}
+
+// cmdList fetches the issues the ui would show and prints them
+func cmdList(opts *Options, target, filter, sortBy, format string) error {
+	if format != "table" && format != "json" && format != "csv" {
+		return fmt.Errorf("Unknown format: %s", format)
+	}
+
+	config, err := LoadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	var api API
+	if fixture := opts.CLI.String("fixture"); fixture != "" {
+		api, err = LoadFakeAPI(fixture)
+	} else {
+		api, err = NewAPI(opts, config)
+	}
+	if err != nil {
+		return err
+	}
+
+	org, target := resolveTarget(config, opts.CLI.String("org"), target)
+	rows, err := listIssues(api, config, org, target, filter, sortBy)
+	if err != nil {
+		return err
+	}
+
+	dimensions := []string{}
+	for _, d := range config.Dimensions {
+		dimensions = append(dimensions, d.Name)
+	}
+
+	switch format {
+	case "json":
+		return printListJSON(os.Stdout, rows)
+	case "csv":
+		return printListCSV(os.Stdout, dimensions, rows)
+	}
+	return printListTable(os.Stdout, dimensions, rows)
+}
+
+// listIssues fetches, filters and sorts issues the same way the ui does
+func listIssues(api API, config *Config, org, target, filter, sortBy string) ([]*ListRow, error) {
+	sortFunc, asc, ok := parseSort(sortBy)
+	if !ok {
+		return nil, fmt.Errorf("Unknown sort: %s", sortBy)
+	}
+
+	milestones := fetchMilestones(api, config)
+
+	fetched := []github.Issue{}
+	for result := range fetchResults(api, org, target, time.Time{}) {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		fetched = append(fetched, result.Issues...)
+	}
+
+	issues := []*Issue{}
+	for _, issue := range fetched {
+		issues = append(issues, NewIssue(issue, milestones, config.MilestoneTiers, config.Dimensions))
+	}
+	issues = filterIssues(issues, filter)
+	sort.Sort(&issueSorter{issues, sortFunc, asc})
+
+	rows := []*ListRow{}
+	for _, issue := range issues {
+		rows = append(rows, NewListRow(issue))
+	}
+	return rows, nil
+}
+
+func printListJSON(out io.Writer, rows []*ListRow) error {
+	data, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+func printListCSV(out io.Writer, dimensions []string, rows []*ListRow) error {
+	w := csv.NewWriter(out)
+	header := []string{"idx", "project", "number", "title", "milestone"}
+	header = append(header, dimensions...)
+	w.Write(append(header, "labels", "url"))
+	for _, row := range rows {
+		record := []string{
+			row.Idx,
+			row.Project,
+			strconv.Itoa(row.Number),
+			row.Title,
+			row.Milestone,
+		}
+		for _, d := range dimensions {
+			record = append(record, row.Dimensions[d])
+		}
+		w.Write(append(record, strings.Join(row.Labels, " "), row.URL))
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printListTable(out io.Writer, dimensions []string, rows []*ListRow) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprint(w, "IDX\tPROJECT\tNUM\tMILESTONE\t")
+	for _, d := range dimensions {
+		fmt.Fprintf(w, "%s\t", strings.ToUpper(d))
+	}
+	fmt.Fprintln(w, "TITLE")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t", row.Idx, row.Project, row.Number, row.Milestone)
+		for _, d := range dimensions {
+			fmt.Fprintf(w, "%s\t", row.Dimensions[d])
+		}
+		fmt.Fprintln(w, row.Title)
+	}
+	return w.Flush()
+}
@@ -9,7 +9,6 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
-	"github.com/google/go-github/github"
 	"github.com/nsf/termbox-go"
 )
 
@@ -25,13 +24,16 @@ var (
 				os.Exit(1)
 			}
 			target := c.Args().First()
-			err = cmdUI(opts, target)
+			err = cmdUI(opts, target, c.String("fixture"))
 			if err != nil {
 				SoftExit(opts, err)
 			}
 		},
 		Flags: []cli.Flag{
 			cli.StringFlag{Name: "org", Usage: "list by org"},
+			cli.StringFlag{Name: "fixture", Usage: "use issues from a raw_issues.json instead of github"},
+			cli.BoolFlag{Name: "no-cache", Usage: "fetch everything instead of using the cache"},
+			cli.BoolFlag{Name: "offline", Usage: "use the cache and queue up changes for `triage sync`"},
 		},
 	}
 )
@@ -47,9 +49,12 @@ func SoftExit(opts *Options, err error) {
 
 // Options are our global options
 type Options struct {
-	APIToken string
-	Debug    bool
-	CLI      *cli.Context
+	APIToken    string
+	GitlabToken string
+	GithubURL   string
+	ConfigFile  string
+	Debug       bool
+	CLI         *cli.Context
 }
 
 // NewOptions constructor
@@ -59,28 +64,68 @@ func NewOptions(c *cli.Context) (*Options, error) {
 		logger.Level = logrus.DebugLevel
 	}
 
+	// fixtures and offline mode don't need to talk to github
 	apiToken := c.GlobalString("api-token")
-	if apiToken == "" {
+	if apiToken == "" && c.String("fixture") == "" && !c.Bool("offline") {
 		return nil, fmt.Errorf("No API token found, please set GITHUB_TOKEN or --api-token")
 	}
 
 	return &Options{
-		APIToken: c.GlobalString("api-token"),
-		Debug:    debug,
-		CLI:      c,
+		APIToken:    c.GlobalString("api-token"),
+		GitlabToken: c.GlobalString("gitlab-token"),
+		GithubURL:   c.GlobalString("github-url"),
+		ConfigFile:  c.GlobalString("config"),
+		Debug:       debug,
+		CLI:         c,
 	}, nil
 }
 
-// AuthClient for github
+// AuthClient for github, it waits out rate limits and retries errors
 func AuthClient(opts *Options) *http.Client {
+	return TokenClient(opts.APIToken)
+}
+
+// TokenClient is an AuthClient with some other token
+func TokenClient(token string) *http.Client {
 	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: opts.APIToken},
+		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(oauth2.NoContext, ts)
+	tc.Transport = NewRetryTransport(tc.Transport, githubRates)
 	return tc
 }
 
-func cmdUI(opts *Options, target string) error {
+// NewAPI for GitHub, or for each GitHub host and GitLab if more than
+// one is configured
+func NewAPI(opts *Options, config *Config) (API, error) {
+	clients := NewGithubClients(opts, config)
+	client, err := clients.Host("")
+	if err != nil {
+		return nil, err
+	}
+	var api API
+	api = NewGithubAPI(client, opts, config)
+
+	others := config.OtherGithubHosts()
+	if config.Gitlab.URL == "" && len(others) == 0 {
+		return api, nil
+	}
+
+	multi := NewMultiAPI(config.GithubHost(), api, config)
+	for _, host := range others {
+		client, err := clients.Host(host)
+		if err != nil {
+			return nil, err
+		}
+		multi.Add(host, NewGithubAPI(client, opts, config))
+	}
+	if config.Gitlab.URL != "" {
+		multi.Add(hostFromURL(config.Gitlab.URL), NewGitlabAPI(&http.Client{Transport: NewRetryTransport(nil, nil)}, opts, config))
+	}
+	return multi, nil
+}
+
+func cmdUI(opts *Options, target, fixture string) error {
 	f, err := os.Create("triage.log")
 	if err != nil {
 		return err
@@ -94,15 +139,38 @@ func cmdUI(opts *Options, target string) error {
 	}
 	defer termbox.Close()
 
-	tc := AuthClient(opts)
-	client := github.NewClient(tc)
 	config, err := LoadConfig(opts)
 	if err != nil {
 		return err
 	}
-	api := NewGithubAPI(client, opts, config)
+	err = validateForUI(config)
+	if err != nil {
+		return err
+	}
+	offline := opts.CLI.Bool("offline")
+	var api API
+	var journal *Journal
+	if fixture != "" {
+		api, err = LoadFakeAPI(fixture)
+		if err != nil {
+			return err
+		}
+	} else if offline {
+		journal, err = LoadJournal()
+		if err != nil {
+			return err
+		}
+		api = NewOfflineAPI(journal)
+	} else {
+		api, err = NewAPI(opts, config)
+		if err != nil {
+			return err
+		}
+	}
 
-	issueWindow := NewTopIssueWindow(client, opts, config, api, target)
+	issueWindow := NewTopIssueWindow(opts, config, api, target)
+	issueWindow.UseCache = fixture == "" && (offline || !opts.CLI.Bool("no-cache"))
+	issueWindow.Journal = journal
 	if err := issueWindow.Init(); err != nil {
 		return err
 	}
@@ -128,13 +196,13 @@ TermLoop:
 
 func printLine(str string, x, y int) {
 	for i := range str {
-		termbox.SetCell(x+i, y, rune(str[i]), termbox.ColorDefault, termbox.ColorDefault)
+		screen.SetCell(x+i, y, rune(str[i]), termbox.ColorDefault, termbox.ColorDefault)
 	}
 }
 
 func printLineColor(str string, x, y int, fg, bg termbox.Attribute) {
 	for i := range str {
-		termbox.SetCell(x+i, y, rune(str[i]), fg, bg)
+		screen.SetCell(x+i, y, rune(str[i]), fg, bg)
 	}
 }
 
@@ -147,17 +215,27 @@ func main() {
 	app.Version = Version()
 	app.Commands = []cli.Command{
 		uiCommand,
+		listCommand,
+		setCommand,
 		showLabelsCommand,
 		setLabelsCommand,
 		showProjectsCommand,
+		validateConfigCommand,
 		showMilestonesCommand,
 		setMilestonesCommand,
 		createMilestoneCommand,
+		syncMilestonesCommand,
+		rolloverCommand,
+		syncCommand,
+		snapshotCommand,
 		versionCommand,
 	}
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{Name: "debug", Usage: "output debug info"},
+		cli.StringFlag{Name: "config", Value: "", Usage: "config file, on top of the user and .triage/config.yml ones", EnvVar: "TRIAGE_CONFIG"},
 		cli.StringFlag{Name: "api-token", Value: "", Usage: "github api token", EnvVar: "GITHUB_TOKEN"},
+		cli.StringFlag{Name: "github-url", Value: "", Usage: "github api url, for GitHub Enterprise", EnvVar: "GITHUB_URL"},
+		cli.StringFlag{Name: "gitlab-token", Value: "", Usage: "gitlab api token", EnvVar: "GITLAB_TOKEN"},
 	}
 	app.Run(os.Args)
 }
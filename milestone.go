@@ -1,13 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"math/rand"
+	"io"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
+	"gopkg.in/yaml.v2"
+
 	"github.com/codegangsta/cli"
 	"github.com/google/go-github/github"
 )
@@ -22,11 +26,14 @@ var (
 				logger.Errorln("Invalid options", err)
 				os.Exit(1)
 			}
-			err = cmdShowMilestones(opts)
+			err = cmdShowMilestones(opts, c.String("format"))
 			if err != nil {
 				SoftExit(opts, err)
 			}
 		},
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "format", Value: "table", Usage: "table, json or yaml"},
+		},
 	}
 	setMilestonesCommand = cli.Command{
 		Name:      "set-milestones",
@@ -39,11 +46,15 @@ var (
 				os.Exit(1)
 			}
 			project := c.Args().First()
-			err = cmdSetMilestones(opts, project)
+			err = cmdSetMilestones(opts, project, c.Bool("dry-run"), c.Bool("diff"))
 			if err != nil {
 				SoftExit(opts, err)
 			}
 		},
+		Flags: []cli.Flag{
+			cli.BoolFlag{Name: "dry-run", Usage: "show what would change without changing it"},
+			cli.BoolFlag{Name: "diff", Usage: "show what would change as a diff, without changing it"},
+		},
 	}
 	createMilestoneCommand = cli.Command{
 		Name:      "create-milestone",
@@ -58,7 +69,7 @@ var (
 			project := c.Args().First()
 			due := c.String("due")
 			title := c.String("title")
-			err = cmdCreateMilestone(opts, project, due, title)
+			err = cmdCreateMilestone(opts, project, due, title, c.Int("count"))
 			if err != nil {
 				SoftExit(opts, err)
 			}
@@ -66,6 +77,7 @@ var (
 		Flags: []cli.Flag{
 			cli.StringFlag{Name: "due", Usage: "due on YYYY-MM-DD"},
 			cli.StringFlag{Name: "title", Usage: "title of the milestone"},
+			cli.IntFlag{Name: "count", Value: 1, Usage: "create this many milestones, one per cadence"},
 		},
 	}
 )
@@ -75,123 +87,271 @@ type Milestone struct {
 	Number int
 	Title  string
 	DueOn  *time.Time
+	// the issue counts are only known if HasCounts, GitLab doesn't give
+	// them to us
+	OpenIssues   int
+	ClosedIssues int
+	HasCounts    bool
 }
 
 // Milestones implemenation of milestones-for-project for github api
 func (a *GithubAPI) Milestones(project string) ([]*Milestone, error) {
 	defer profile("GithubAPI.Milestones").Stop()
+	ours, err := a.OpenMilestones(project)
+	if err != nil {
+		return nil, err
+	}
+	return triageMilestones(project, ours, a.config)
+}
+
+// OpenMilestones are all of a project's open milestones
+func (a *GithubAPI) OpenMilestones(project string) ([]*Milestone, error) {
 	owner, repo, err := ownerRepo(project)
 	if err != nil {
 		return nil, err
 	}
 
 	logger.Debugln("Fetching milestones for:", project)
-	milestones, _, err := a.client.Issues.ListMilestones(
-		owner,
-		repo,
-		&github.MilestoneListOptions{},
-	)
+	milestones, err := listMilestones(a.client, owner, repo, "open")
+	if err != nil {
+		return nil, err
+	}
+
+	ours := []*Milestone{}
+	for _, milestone := range milestones {
+		m := &Milestone{
+			Number: *milestone.Number,
+			Title:  *milestone.Title,
+			DueOn:  milestone.DueOn,
+		}
+		m.HasCounts = milestone.OpenIssues != nil && milestone.ClosedIssues != nil
+		if milestone.OpenIssues != nil {
+			m.OpenIssues = *milestone.OpenIssues
+		}
+		if milestone.ClosedIssues != nil {
+			m.ClosedIssues = *milestone.ClosedIssues
+		}
+		ours = append(ours, m)
+	}
+	return ours, nil
+}
+
+// MilestoneIssues are all the open issues in a milestone
+func (a *GithubAPI) MilestoneIssues(project string, milestone *Milestone) ([]github.Issue, error) {
+	owner, repo, err := ownerRepo(project)
 	if err != nil {
 		return nil, err
 	}
+	return milestoneIssues(a.client, owner, repo, milestone.Number)
+}
 
-	var current *Milestone
-	var next *Milestone
-	var someday *Milestone
+// CloseMilestone marks a milestone closed
+func (a *GithubAPI) CloseMilestone(project string, milestone *Milestone) error {
+	owner, repo, err := ownerRepo(project)
+	if err != nil {
+		return err
+	}
+	_, _, err = a.client.Issues.EditMilestone(owner, repo, milestone.Number, &github.Milestone{State: github.String("closed")})
+	return err
+}
 
+// triageMilestones picks a milestone for each of our tiers out of
+// everything a project has, regardless of which tracker they came from
+func triageMilestones(project string, milestones []*Milestone, config *Config) ([]*Milestone, error) {
+	now := time.Now()
+	upcoming := []*Milestone{}
 	for _, milestone := range milestones {
-		logger.Debugf("  found milestone: (%d) %s %v", *milestone.Number, *milestone.Title, milestone.DueOn)
-		if current == nil && milestone.DueOn != nil {
-			if (*milestone.DueOn).After(time.Now()) {
-				current = &Milestone{
-					Number: *milestone.Number,
-					Title:  *milestone.Title,
-					DueOn:  milestone.DueOn,
-				}
-				logger.Debugln("    using as Current")
-			}
-		} else if milestone.DueOn == nil {
-			if *milestone.Title == a.config.NextMilestone {
-				next = &Milestone{
-					Number: *milestone.Number,
-					Title:  *milestone.Title,
-				}
-				logger.Debugln("    using as Next")
+		logger.Debugf("  found milestone: (%d) %s %v", milestone.Number, milestone.Title, milestone.DueOn)
+		if milestone.DueOn != nil && milestone.DueOn.After(now) {
+			upcoming = append(upcoming, milestone)
+		}
+	}
+	// the nearest due date after now is the first dated tier, and so on
+	sort.Sort(byDueOn(upcoming))
+
+	tiers := make([]*Milestone, len(config.MilestoneTiers))
+	missing := []string{}
+	dated := 0
+	for i, tier := range config.MilestoneTiers {
+		if tier.Dated {
+			if dated < len(upcoming) {
+				tiers[i] = upcoming[dated]
 			}
-			if *milestone.Title == a.config.SomedayMilestone {
-				someday = &Milestone{
-					Number: *milestone.Number,
-					Title:  *milestone.Title,
+			dated++
+		} else {
+			for _, milestone := range milestones {
+				if milestone.DueOn == nil && milestone.Title == tier.Title {
+					tiers[i] = milestone
 				}
-				logger.Debugln("    using as Someday")
 			}
 		}
+		if tiers[i] == nil {
+			missing = append(missing, tier.Name)
+			continue
+		}
+		logger.Debugf("    using %s as %s", tiers[i].Title, tier.Name)
 	}
 
-	if current == nil || next == nil || someday == nil {
-		return []*Milestone{current, next, someday}, fmt.Errorf("Did not find valid milestones for: %s (%v, %v, %v)", project, current, next, someday)
+	if len(missing) > 0 {
+		return tiers, fmt.Errorf("Did not find valid milestones for: %s (no %s)", project, strings.Join(missing, ", "))
 	}
+	return tiers, nil
+}
+
+// byDueOn sorts milestones with due dates, soonest first
+type byDueOn []*Milestone
+
+// Len for Sortable
+func (ms byDueOn) Len() int {
+	return len(ms)
+}
+
+// Swap for Sortable
+func (ms byDueOn) Swap(i, j int) {
+	ms[i], ms[j] = ms[j], ms[i]
+}
+
+// Less for Sortable
+func (ms byDueOn) Less(i, j int) bool {
+	return ms[i].DueOn.Before(*ms[j].DueOn)
+}
+
+// MilestoneRow is what show-milestones prints for each tier of a project
+type MilestoneRow struct {
+	Project string `json:"project" yaml:"project"`
+	Tier    string `json:"tier" yaml:"tier"`
+	// the rest are empty if the project has no milestone for the tier,
+	// and the counts if we don't know them
+	Number  int    `json:"number,omitempty" yaml:"number,omitempty"`
+	Title   string `json:"title,omitempty" yaml:"title,omitempty"`
+	Due     string `json:"due,omitempty" yaml:"due,omitempty"`
+	Open    *int   `json:"open,omitempty" yaml:"open,omitempty"`
+	Closed  *int   `json:"closed,omitempty" yaml:"closed,omitempty"`
+	Percent *int   `json:"percent,omitempty" yaml:"percent,omitempty"`
+}
 
-	return []*Milestone{current, next, someday}, nil
+// NewMilestoneRow for a project's milestone in a tier, m can be nil
+func NewMilestoneRow(project string, tier MilestoneTier, m *Milestone) *MilestoneRow {
+	row := &MilestoneRow{Project: project, Tier: tier.Name}
+	if m == nil {
+		return row
+	}
+	row.Number = m.Number
+	row.Title = m.Title
+	if m.DueOn != nil {
+		row.Due = dueDate(*m.DueOn)
+	}
+	if !m.HasCounts {
+		return row
+	}
+	open, closed, percent := m.OpenIssues, m.ClosedIssues, 0
+	if total := open + closed; total > 0 {
+		percent = closed * 100 / total
+	}
+	row.Open, row.Closed, row.Percent = &open, &closed, &percent
+	return row
 }
 
 // cmdShowMilestones prints the milestones we detected on your projects
-func cmdShowMilestones(opts *Options) error {
-	tc := AuthClient(opts)
-	client := github.NewClient(tc)
+func cmdShowMilestones(opts *Options, format string) error {
+	if format != "table" && format != "json" && format != "yaml" {
+		return fmt.Errorf("Unknown format: %s", format)
+	}
 
 	config, err := LoadConfig(opts)
 	if err != nil {
 		return err
 	}
 
-	api := NewGithubAPI(client, opts, config)
+	api, err := NewAPI(opts, config)
+	if err != nil {
+		return err
+	}
 
-	out := map[string][]*Milestone{}
-	for _, project := range config.Projects {
+	rows := milestoneRows(api, config)
+	switch format {
+	case "json":
+		return printMilestonesJSON(os.Stdout, rows)
+	case "yaml":
+		return printMilestonesYAML(os.Stdout, rows)
+	}
+	return printMilestonesTable(os.Stdout, rows)
+}
+
+// milestoneRows are a row for each tier of each project, in the order
+// they're configured
+func milestoneRows(api API, config *Config) []*MilestoneRow {
+	rows := []*MilestoneRow{}
+	for _, project := range config.AllProjects() {
 		milestones, err := api.Milestones(project)
 		if err != nil {
 			logger.Errorln(err)
 		}
-		out[project] = milestones
+		for i, tier := range config.MilestoneTiers {
+			var m *Milestone
+			if i < len(milestones) {
+				m = milestones[i]
+			}
+			rows = append(rows, NewMilestoneRow(project, tier, m))
+		}
+	}
+	return rows
+}
 
+func printMilestonesJSON(out io.Writer, rows []*MilestoneRow) error {
+	data, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return err
 	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
 
-	for project, milestones := range out {
-		fmt.Printf("%s:\n", project)
-		for i, milestone := range milestones {
-			switch i {
-			case 0:
-				fmt.Printf("  current: ")
-			case 1:
-				fmt.Printf("     next: ")
-			case 2:
-				fmt.Printf("  someday: ")
-			}
-			if milestone != nil {
-				fmt.Printf("(%d) %s", milestone.Number, milestone.Title)
-				if milestone.DueOn != nil {
-					fmt.Printf(" %v", milestone.DueOn)
-				}
-				fmt.Printf("\n")
-			} else {
-				fmt.Printf("nil\n")
-			}
+func printMilestonesYAML(out io.Writer, rows []*MilestoneRow) error {
+	data, err := yaml.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s", data)
+	return err
+}
+
+func printMilestonesTable(out io.Writer, rows []*MilestoneRow) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tTIER\tMILESTONE\tDUE\tOPEN\tCLOSED\tDONE")
+	for _, row := range rows {
+		if row.Title == "" {
+			fmt.Fprintf(w, "%s\t%s\t-\t\t\t\t\n", row.Project, row.Tier)
+			continue
+		}
+		due := row.Due
+		if due == "" {
+			due = "-"
 		}
+		if row.Open == nil {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t-\t-\t-\n", row.Project, row.Tier, row.Title, due)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d%%\n", row.Project, row.Tier, row.Title, due, *row.Open, *row.Closed, *row.Percent)
 	}
-	return nil
+	return w.Flush()
 }
 
-// cmdSetMilestones sets our Next and Someday milestones in target projects
-func cmdSetMilestones(opts *Options, target string) error {
-	tc := AuthClient(opts)
-	client := github.NewClient(tc)
+// cmdSetMilestones creates the milestones for our undated tiers in target
+// projects, or just shows what it would do
+func cmdSetMilestones(opts *Options, target string, dryRun, diff bool) error {
 	config, err := LoadConfig(opts)
 	if err != nil {
 		return err
 	}
+	clients := NewGithubClients(opts, config)
 
-	ourMilestones := []string{config.NextMilestone, config.SomedayMilestone}
+	ourMilestones := []string{}
+	for _, tier := range config.MilestoneTiers {
+		if !tier.Dated {
+			ourMilestones = append(ourMilestones, tier.Title)
+		}
+	}
 
 	var projects []string
 	if target == "all" {
@@ -203,75 +363,109 @@ func cmdSetMilestones(opts *Options, target string) error {
 	for _, project := range projects {
 		logger.Debugln("Setting milestones for:", project)
 
-		owner, repo, err := ownerRepo(project)
+		client, owner, repo, err := clients.For(project)
 		if err != nil {
 			return err
 		}
 
-		theirMilestones, _, err := client.Issues.ListMilestones(owner, repo, &github.MilestoneListOptions{})
+		theirs, err := listMilestones(client, owner, repo, "all")
 		if err != nil {
 			return err
 		}
-
-		theirMilestonesList := []string{}
-		for _, milestone := range theirMilestones {
-			theirMilestonesList = append(theirMilestonesList, *milestone.Title)
+		plan := planMilestones(client, project, owner, repo, theirs, ourMilestones)
+		if dryRun || diff {
+			plan.Print(diff)
+			continue
+		}
+		err = plan.Apply()
+		if err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-	OurMilestones:
-		for _, ours := range ourMilestones {
-			for _, theirs := range theirMilestonesList {
-				if ours == theirs {
-					logger.Debugln("  found existing:", ours)
-					continue OurMilestones
-				}
+// planMilestones works out which of our milestones a project is missing,
+// theirs includes closed ones since those can't be created again
+func planMilestones(client *github.Client, project, owner, repo string, theirs []github.Milestone, ourMilestones []string) *Plan {
+	plan := &Plan{Project: project}
+OurMilestones:
+	for _, ours := range ourMilestones {
+		ours := ours
+		for _, m := range theirs {
+			if ours != *m.Title {
+				continue
 			}
-			// if we got here we didn't match, create a milestone
-			logger.Debugln("  creating:", ours)
-			_, _, err := client.Issues.CreateMilestone(owner, repo, &github.Milestone{Title: &ours})
-			if err != nil {
-				return err
+			logger.Debugln("  found existing:", ours)
+			if m.State != nil && *m.State == "closed" {
+				number := *m.Number
+				plan.Add(&Change{
+					Action: "update",
+					Kind:   "milestone",
+					Name:   ours,
+					From:   "closed",
+					To:     "open",
+					apply: func() error {
+						_, _, err := client.Issues.EditMilestone(owner, repo, number, &github.Milestone{State: github.String("open")})
+						return err
+					},
+				})
 			}
+			continue OurMilestones
 		}
+		// if we got here we didn't match, create a milestone
+		plan.Add(&Change{
+			Action: "create",
+			Kind:   "milestone",
+			Name:   ours,
+			apply: func() error {
+				_, _, err := client.Issues.CreateMilestone(owner, repo, &github.Milestone{Title: &ours})
+				return err
+			},
+		})
 	}
-	return nil
+	return plan
 }
 
-// cmdCreateMilestone creates a new milestone in all projects
-func cmdCreateMilestone(opts *Options, target, due, title string) error {
-	tc := AuthClient(opts)
-	client := github.NewClient(tc)
+// cmdCreateMilestone creates the next milestones in our cadence in all
+// projects, skipping any a project already has
+func cmdCreateMilestone(opts *Options, target, due, title string, count int) error {
+	if count < 1 {
+		return fmt.Errorf("Can't create %d milestones", count)
+	}
+	if title != "" && count > 1 {
+		return fmt.Errorf("Can't use one title for %d milestones", count)
+	}
+
 	config, err := LoadConfig(opts)
 	if err != nil {
 		return err
 	}
+	clients := NewGithubClients(opts, config)
+
+	schedule, err := config.Cadence.Schedule()
+	if err != nil {
+		return err
+	}
 
-	var date time.Time
+	var dates []time.Time
 	if due != "" {
-		date, err = time.Parse("2006-01-02", due)
+		date, err := time.ParseInLocation("2006-01-02", due, schedule.Loc)
 		if err != nil {
 			return err
 		}
+		dates = schedule.From(date, count)
 	} else {
-		// Default to Monday next week with a little wiggle room
-		check := time.Now().Add(5 * 24 * time.Hour)
-		check = check.Round(1 * 24 * time.Hour)
-		check = check.Add(1 * time.Second)
-		for check.Weekday() != 0 {
-			check = check.Add(1 * 24 * time.Hour)
-		}
-		date = check
+		dates = schedule.Upcoming(time.Now(), count)
 	}
 
-	if title == "" {
-		year, week := date.ISOWeek()
-		seed, err := strconv.Atoi(fmt.Sprintf("%d%02d", year, week))
-		if err != nil {
-			return err
+	titles := []string{}
+	for _, date := range dates {
+		if title != "" {
+			titles = append(titles, title)
+			continue
 		}
-		rand.Seed(int64(seed))
-		ship := Titles[rand.Intn(len(Titles))]
-		title = fmt.Sprintf("%d-%02d %s", year, week, ship)
+		titles = append(titles, schedule.Title(date))
 	}
 
 	var projects []string
@@ -282,20 +476,69 @@ func cmdCreateMilestone(opts *Options, target, due, title string) error {
 	}
 
 	for _, project := range projects {
-		logger.Debugln("Creating milestone for:", project)
+		logger.Debugln("Creating milestones for:", project)
 
-		owner, repo, err := ownerRepo(project)
+		client, owner, repo, err := clients.For(project)
 		if err != nil {
 			return err
 		}
 
-		// if we got here we didn't match, create a milestone
-		logger.Debugf("  creating: %s (%v)", title, due)
-		_, _, err = client.Issues.CreateMilestone(owner, repo, &github.Milestone{Title: &title, DueOn: &date})
+		theirs, err := listMilestones(client, owner, repo, "all")
 		if err != nil {
 			return err
 		}
+		existing := map[string]bool{}
+		for _, m := range theirs {
+			existing[*m.Title] = true
+		}
+		for _, other := range shadowing(theirs, dates[0], time.Now()) {
+			if !isTitle(titles, *other.Title) {
+				logger.Warnf("%s won't be Current in %s, %s is due sooner (%s)", titles[0], project, *other.Title, dueDate(*other.DueOn))
+			}
+		}
+
+		for i, date := range dates {
+			if existing[titles[i]] {
+				logger.Debugln("  found existing:", titles[i])
+				continue
+			}
+			date := date
+			logger.Debugf("  creating: %s (%v)", titles[i], date)
+			_, _, err = client.Issues.CreateMilestone(owner, repo, &github.Milestone{Title: &titles[i], DueOn: &date})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for i, date := range dates {
+		fmt.Printf("New Milestone: %s (due %s)\n", titles[i], date.Format("2006-01-02"))
 	}
-	fmt.Printf("New Milestone: %s\n", title)
 	return nil
 }
+
+// isTitle checks whether title is one of titles
+func isTitle(titles []string, title string) bool {
+	for _, t := range titles {
+		if t == title {
+			return true
+		}
+	}
+	return false
+}
+
+// listMilestones gets all of a project's milestones in a state
+func listMilestones(client *github.Client, owner, repo, state string) ([]github.Milestone, error) {
+	opt := &github.MilestoneListOptions{State: state, ListOptions: github.ListOptions{PerPage: 100}}
+	milestones := []github.Milestone{}
+	for {
+		page, resp, err := client.Issues.ListMilestones(owner, repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		milestones = append(milestones, page...)
+		if resp.NextPage == 0 {
+			return milestones, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
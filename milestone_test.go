@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestMilestoneRows(t *testing.T) {
+	config := testConfig()
+	config.Gitlab = GitlabConfig{URL: "https://gitlab.corp", Projects: Projects{"group/repo"}}
+	due := time.Date(2016, 3, 7, 0, 0, 0, 0, time.UTC)
+	api := NewFakeAPI(nil, map[string][]*Milestone{
+		"wercker/foo": {
+			{Number: 4, Title: "Zealot", DueOn: &due, OpenIssues: 3, ClosedIssues: 1, HasCounts: true},
+			{Number: 2, Title: "Next", HasCounts: true},
+			{Number: 3, Title: "Someday", OpenIssues: 10, HasCounts: true},
+		},
+		// no counts from GitLab
+		"group/repo": {{Number: 7, Title: "Zealot", DueOn: &due}, nil, nil},
+	})
+
+	rows := milestoneRows(api, config)
+	out := &bytes.Buffer{}
+	if err := printMilestonesTable(out, rows); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"PROJECT      TIER     MILESTONE  DUE         OPEN  CLOSED  DONE",
+		"wercker/foo  current  Zealot     2016-03-07  3     1       25%",
+		"wercker/foo  next     Next       -           0     0       0%",
+		"wercker/foo  someday  Someday    -           10    0       0%",
+		"wercker/bar  current  -",
+		"wercker/bar  next     -",
+		"wercker/bar  someday  -",
+		"group/repo   current  Zealot     2016-03-07  -     -       -",
+		"group/repo   next     -",
+		"group/repo   someday  -",
+	}
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	if got := strings.Join(lines, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), got)
+	}
+
+	// unknown counts are left out rather than zero
+	out.Reset()
+	if err := printMilestonesJSON(out, rows[6:7]); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "open") || !strings.Contains(out.String(), `"title": "Zealot"`) {
+		t.Errorf("wrong json: %s", out.String())
+	}
+}
+
+func TestPlanMilestones(t *testing.T) {
+	theirs := []github.Milestone{
+		{Number: github.Int(1), Title: github.String("Next"), State: github.String("open")},
+		{Number: github.Int(2), Title: github.String("Someday"), State: github.String("closed")},
+		{Number: github.Int(3), Title: github.String("Zealot"), State: github.String("open")},
+	}
+	tests := []struct {
+		theirs   []github.Milestone
+		expected string
+	}{
+		{theirs, "update milestone Someday: closed -> open"},
+		{theirs[:1], "create milestone Someday"},
+		{nil, "create milestone Next; create milestone Someday"},
+	}
+	for _, test := range tests {
+		plan := planMilestones(nil, "wercker/foo", "wercker", "foo", test.theirs, []string{"Next", "Someday"})
+		changes := []string{}
+		for _, change := range plan.Changes {
+			changes = append(changes, change.String())
+		}
+		if got := strings.Join(changes, "; "); got != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/google/go-github/github"
+)
+
+var (
+	syncCommand = cli.Command{
+		Name:  "sync",
+		Usage: "replay changes made with `ui --offline`",
+		Action: func(c *cli.Context) {
+			opts, err := NewOptions(c)
+			if err != nil {
+				logger.Errorln("Invalid options", err)
+				os.Exit(1)
+			}
+			err = cmdSync(opts, c.Bool("force"))
+			if err != nil {
+				SoftExit(opts, err)
+			}
+		},
+		Flags: []cli.Flag{
+			cli.BoolFlag{Name: "force", Usage: "apply changes even if the issue changed remotely"},
+		},
+	}
+)
+
+// JournalEntry is a change made while offline, along with what the issue
+// looked like beforehand so we can tell if somebody else changed it since
+type JournalEntry struct {
+	Time      time.Time
+	URL       string
+	Owner     string
+	Repo      string
+	Number    int
+	Op        string
+	Value     string
+	Labels    []string
+	Logins    []string
+	Milestone *Milestone
+
+	BeforeLabels    []string
+	BeforeMilestone *Milestone
+}
+
+// Issue is enough of an Issue to hand to an API
+func (e *JournalEntry) Issue() *Issue {
+	return &Issue{
+		Number:  e.Number,
+		URL:     e.URL,
+		Owner:   e.Owner,
+		Repo:    e.Repo,
+		Project: fmt.Sprintf("%s/%s", e.Owner, e.Repo),
+	}
+}
+
+// String describes the change
+func (e *JournalEntry) String() string {
+	ref := fmt.Sprintf("%s/%s#%d", e.Owner, e.Repo, e.Number)
+	switch e.Op {
+	case "milestone":
+		return fmt.Sprintf("%s milestone -> %s", ref, milestoneTitle(e.Milestone))
+	case "labels":
+		return fmt.Sprintf("%s labels -> [%s]", ref, strings.Join(e.Labels, " "))
+	case "assignees":
+		return fmt.Sprintf("%s assignees -> [%s]", ref, strings.Join(e.Logins, " "))
+	case "comment":
+		comment := strings.SplitN(strings.TrimSpace(e.Value), "\n", 2)[0]
+		if len(comment) > 40 {
+			comment = comment[:40] + "..."
+		}
+		return fmt.Sprintf("%s comment %q", ref, comment)
+	}
+	return fmt.Sprintf("%s %s %s", ref, e.Op, e.Value)
+}
+
+// milestoneTitle for display, nil means no milestone
+func milestoneTitle(m *Milestone) string {
+	if m == nil {
+		return "none"
+	}
+	return m.Title
+}
+
+// Journal is the queue of changes made while offline
+type Journal struct {
+	Entries []*JournalEntry
+
+	path string
+}
+
+// LoadJournal from the cache dir, a missing journal is an empty one
+func LoadJournal() (*Journal, error) {
+	journal := &Journal{path: filepath.Join(cacheDir(), "journal.json")}
+
+	ok, err := exists(journal.path)
+	if err != nil || !ok {
+		return journal, err
+	}
+
+	data, err := ioutil.ReadFile(journal.path)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, journal)
+	if err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// Save the journal, removing the file once it's empty
+func (j *Journal) Save() error {
+	if len(j.Entries) == 0 {
+		err := os.Remove(j.path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err := os.MkdirAll(filepath.Dir(j.path), 0700)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(j.path, data, 0600)
+}
+
+// Record a change to an issue and save right away
+func (j *Journal) Record(issue *Issue, entry *JournalEntry) error {
+	entry.Time = time.Now()
+	entry.URL = issue.URL
+	entry.Owner = issue.Owner
+	entry.Repo = issue.Repo
+	entry.Number = issue.Number
+	entry.BeforeLabels = append([]string{}, issue.Labels...)
+	entry.BeforeMilestone = issue.Milestone.Milestone
+	j.Entries = append(j.Entries, entry)
+	return j.Save()
+}
+
+// Apply the queued changes to some issues, so that what we show offline
+// includes the changes made in earlier offline sessions
+func (j *Journal) Apply(issues []github.Issue) []github.Issue {
+	byURL := map[string]*github.Issue{}
+	out := make([]github.Issue, len(issues))
+	for i := range issues {
+		out[i] = issues[i]
+		byURL[*issues[i].HTMLURL] = &out[i]
+	}
+
+	for _, entry := range j.Entries {
+		issue, ok := byURL[entry.URL]
+		if !ok {
+			continue
+		}
+		switch entry.Op {
+		case "milestone":
+			issue.Milestone = nil
+			if entry.Milestone != nil {
+				issue.Milestone = &github.Milestone{
+					Number: github.Int(entry.Milestone.Number),
+					Title:  github.String(entry.Milestone.Title),
+					DueOn:  entry.Milestone.DueOn,
+				}
+			}
+		case "labels":
+			issue.Labels = nil
+			for _, label := range entry.Labels {
+				issue.Labels = append(issue.Labels, github.Label{Name: github.String(label)})
+			}
+		case "add-label":
+			issue.Labels = append(append([]github.Label{}, issue.Labels...), github.Label{Name: github.String(entry.Value)})
+		case "remove-label":
+			labels := []github.Label{}
+			for _, label := range issue.Labels {
+				if *label.Name != entry.Value {
+					labels = append(labels, label)
+				}
+			}
+			issue.Labels = labels
+		case "state":
+			issue.State = github.String(entry.Value)
+		case "assignees":
+			issue.Assignees = nil
+			for _, login := range entry.Logins {
+				issue.Assignees = append(issue.Assignees, &github.User{Login: github.String(login)})
+			}
+			// or the old single assignee would show up again
+			issue.Assignee = nil
+			if len(issue.Assignees) > 0 {
+				issue.Assignee = issue.Assignees[0]
+			}
+		}
+	}
+	return out
+}
+
+// Replay a single entry against a live API
+func (e *JournalEntry) Replay(api API) error {
+	issue := e.Issue()
+	switch e.Op {
+	case "milestone":
+		return api.SetMilestone(issue, e.Milestone)
+	case "labels":
+		return api.ReplaceLabels(issue, e.Labels)
+	case "add-label":
+		return api.AddLabel(issue, e.Value)
+	case "remove-label":
+		return api.RemoveLabel(issue, e.Value)
+	case "state":
+		return api.SetState(issue, e.Value)
+	case "assignees":
+		return api.SetAssignees(issue, e.Logins)
+	case "comment":
+		return api.AddComment(issue, e.Value)
+	}
+	return fmt.Errorf("Unknown journal op: %s", e.Op)
+}
+
+// Conflict checks whether the remote issue still looks like it did when
+// the change was made, returning a description of the difference if not
+func (e *JournalEntry) Conflict(remote *github.Issue) string {
+	// a comment still makes sense whatever happened to the issue
+	if e.Op == "comment" {
+		return ""
+	}
+	conflicts := []string{}
+
+	remoteM := 0
+	if remote.Milestone != nil {
+		remoteM = *remote.Milestone.Number
+	}
+	beforeM := 0
+	if e.BeforeMilestone != nil {
+		beforeM = e.BeforeMilestone.Number
+	}
+	if remoteM != beforeM {
+		title := "none"
+		if remote.Milestone != nil {
+			title = *remote.Milestone.Title
+		}
+		conflicts = append(conflicts, fmt.Sprintf("milestone was %s, now %s", milestoneTitle(e.BeforeMilestone), title))
+	}
+
+	remoteLabels := []string{}
+	for _, label := range remote.Labels {
+		remoteLabels = append(remoteLabels, *label.Name)
+	}
+	before := append([]string{}, e.BeforeLabels...)
+	sort.Strings(remoteLabels)
+	sort.Strings(before)
+	if strings.Join(remoteLabels, " ") != strings.Join(before, " ") {
+		conflicts = append(conflicts, fmt.Sprintf("labels were [%s], now [%s]", strings.Join(before, " "), strings.Join(remoteLabels, " ")))
+	}
+
+	return strings.Join(conflicts, ", ")
+}
+
+// OfflineAPI can't fetch anything, it writes changes to the journal so
+// they can be replayed with `triage sync`
+type OfflineAPI struct {
+	journal *Journal
+}
+
+// NewOfflineAPI constructor
+func NewOfflineAPI(journal *Journal) *OfflineAPI {
+	return &OfflineAPI{journal}
+}
+
+// errOffline is what you get for asking the OfflineAPI for anything
+var errOffline = fmt.Errorf("Can't do that while offline")
+
+// offlineResults is a result channel containing only errOffline
+func offlineResults() <-chan *IssueResult {
+	out := make(chan *IssueResult, 1)
+	out <- &IssueResult{nil, errOffline}
+	close(out)
+	return out
+}
+
+// Milestones aren't available offline, they come from the cache
+func (a *OfflineAPI) Milestones(project string) ([]*Milestone, error) {
+	return nil, errOffline
+}
+
+// OpenMilestones aren't available offline
+func (a *OfflineAPI) OpenMilestones(project string) ([]*Milestone, error) {
+	return nil, errOffline
+}
+
+// MilestoneIssues aren't available offline
+func (a *OfflineAPI) MilestoneIssues(project string, milestone *Milestone) ([]github.Issue, error) {
+	return nil, errOffline
+}
+
+// Search isn't available offline
+func (a *OfflineAPI) Search(query string, since time.Time) <-chan *IssueResult {
+	return offlineResults()
+}
+
+// ByOrg isn't available offline
+func (a *OfflineAPI) ByOrg(org string, since time.Time) <-chan *IssueResult {
+	return offlineResults()
+}
+
+// ByUser isn't available offline
+func (a *OfflineAPI) ByUser(since time.Time) <-chan *IssueResult {
+	return offlineResults()
+}
+
+// Get isn't available offline
+func (a *OfflineAPI) Get(issue *Issue) (*github.Issue, error) {
+	return nil, errOffline
+}
+
+// Collaborators aren't available offline
+func (a *OfflineAPI) Collaborators(project string) ([]string, error) {
+	return nil, errOffline
+}
+
+// Comments aren't cached, so they aren't available offline
+func (a *OfflineAPI) Comments(issue *Issue) ([]*Comment, error) {
+	return nil, errOffline
+}
+
+// SetMilestone is queued in the journal
+func (a *OfflineAPI) SetMilestone(issue *Issue, milestone *Milestone) error {
+	return a.journal.Record(issue, &JournalEntry{Op: "milestone", Milestone: milestone})
+}
+
+// ReplaceLabels is queued in the journal
+func (a *OfflineAPI) ReplaceLabels(issue *Issue, labels []string) error {
+	return a.journal.Record(issue, &JournalEntry{Op: "labels", Labels: labels})
+}
+
+// AddLabel is queued in the journal
+func (a *OfflineAPI) AddLabel(issue *Issue, label string) error {
+	return a.journal.Record(issue, &JournalEntry{Op: "add-label", Value: label})
+}
+
+// RemoveLabel is queued in the journal
+func (a *OfflineAPI) RemoveLabel(issue *Issue, label string) error {
+	return a.journal.Record(issue, &JournalEntry{Op: "remove-label", Value: label})
+}
+
+// SetState is queued in the journal
+func (a *OfflineAPI) SetState(issue *Issue, state string) error {
+	return a.journal.Record(issue, &JournalEntry{Op: "state", Value: state})
+}
+
+// SetAssignees is queued in the journal
+func (a *OfflineAPI) SetAssignees(issue *Issue, logins []string) error {
+	return a.journal.Record(issue, &JournalEntry{Op: "assignees", Logins: logins})
+}
+
+// AddComment is queued in the journal
+func (a *OfflineAPI) AddComment(issue *Issue, body string) error {
+	return a.journal.Record(issue, &JournalEntry{Op: "comment", Value: body})
+}
+
+// CloseMilestone isn't something the journal can hold
+func (a *OfflineAPI) CloseMilestone(project string, milestone *Milestone) error {
+	return errOffline
+}
+
+// Replay the journal against a live API, keeping anything that fails or
+// conflicts along with every later change to the same issue, so that the
+// changes to an issue always land in the order they were made
+func (j *Journal) Replay(api API, force bool) int {
+	remaining := []*JournalEntry{}
+	held := map[string]bool{}
+	conflicts := 0
+	for _, entry := range j.Entries {
+		if held[entry.URL] {
+			fmt.Printf("    held: %s: an earlier change to it wasn't synced\n", entry)
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		remote, err := api.Get(entry.Issue())
+		if err != nil {
+			fmt.Printf("  failed: %s: %s\n", entry, err)
+			remaining = append(remaining, entry)
+			held[entry.URL] = true
+			continue
+		}
+
+		if conflict := entry.Conflict(remote); conflict != "" && !force {
+			fmt.Printf("conflict: %s: %s\n", entry, conflict)
+			remaining = append(remaining, entry)
+			held[entry.URL] = true
+			conflicts++
+			continue
+		}
+
+		err = entry.Replay(api)
+		if err != nil {
+			fmt.Printf("  failed: %s: %s\n", entry, err)
+			remaining = append(remaining, entry)
+			held[entry.URL] = true
+			continue
+		}
+		fmt.Printf("  synced: %s\n", entry)
+	}
+	j.Entries = remaining
+	return conflicts
+}
+
+// cmdSync replays the journal, skipping (and keeping) anything that
+// conflicts with changes made remotely unless forced
+func cmdSync(opts *Options, force bool) error {
+	config, err := LoadConfig(opts)
+	if err != nil {
+		return err
+	}
+	api, err := NewAPI(opts, config)
+	if err != nil {
+		return err
+	}
+
+	journal, err := LoadJournal()
+	if err != nil {
+		return err
+	}
+	if len(journal.Entries) == 0 {
+		fmt.Println("Nothing to sync")
+		return nil
+	}
+
+	conflicts := journal.Replay(api, force)
+	err = journal.Save()
+	if err != nil {
+		return err
+	}
+	if len(journal.Entries) > 0 {
+		return fmt.Errorf("%d changes not synced (%d conflicts), they'll be retried next time, use --force to overwrite conflicts", len(journal.Entries), conflicts)
+	}
+	return nil
+}
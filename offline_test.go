@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// testJournalAPI has two issues in the Next milestone with no labels
+func testJournalAPI() *FakeAPI {
+	issue := func(number int) github.Issue {
+		return github.Issue{
+			Number:    github.Int(number),
+			Title:     github.String("Thing"),
+			HTMLURL:   github.String(fmt.Sprintf("https://github.com/wercker/foo/issues/%d", number)),
+			Milestone: &github.Milestone{Number: github.Int(2), Title: github.String("Next")},
+		}
+	}
+	return NewFakeAPI([]github.Issue{issue(1), issue(2)}, nil)
+}
+
+// journalEntry changes an issue that was in Next with no labels
+func journalEntry(number int, op string, labels ...string) *JournalEntry {
+	return &JournalEntry{
+		URL:             fmt.Sprintf("https://github.com/wercker/foo/issues/%d", number),
+		Owner:           "wercker",
+		Repo:            "foo",
+		Number:          number,
+		Op:              op,
+		Labels:          labels,
+		Milestone:       &Milestone{Number: 3, Title: "Someday"},
+		BeforeMilestone: &Milestone{Number: 2, Title: "Next"},
+		BeforeLabels:    []string{},
+	}
+}
+
+func TestJournalReplay(t *testing.T) {
+	conflicted := journalEntry(1, "milestone")
+	conflicted.BeforeMilestone = &Milestone{Number: 1, Title: "Current"}
+	// made after labelling it bug
+	retyped := journalEntry(1, "labels", "task")
+	retyped.BeforeLabels = []string{"bug"}
+
+	tests := []struct {
+		name      string
+		force     bool
+		entries   []*JournalEntry
+		calls     []string
+		kept      []string
+		conflicts int
+	}{
+		{
+			name:    "everything syncs in order",
+			entries: []*JournalEntry{journalEntry(1, "labels", "bug"), retyped, journalEntry(2, "milestone")},
+			calls:   []string{"ReplaceLabels wercker/foo#1 bug", "ReplaceLabels wercker/foo#1 task", "SetMilestone wercker/foo#2 3"},
+		},
+		{
+			name:      "a conflict holds later changes to the same issue",
+			entries:   []*JournalEntry{conflicted, journalEntry(1, "labels", "bug"), journalEntry(2, "milestone")},
+			calls:     []string{"SetMilestone wercker/foo#2 3"},
+			kept:      []string{"wercker/foo#1 milestone -> Someday", "wercker/foo#1 labels -> [bug]"},
+			conflicts: 1,
+		},
+		{
+			name:    "forcing a conflict keeps the order",
+			force:   true,
+			entries: []*JournalEntry{conflicted, journalEntry(1, "labels", "bug")},
+			calls:   []string{"SetMilestone wercker/foo#1 3", "ReplaceLabels wercker/foo#1 bug"},
+		},
+		{
+			name:    "a failure holds later changes to the same issue",
+			entries: []*JournalEntry{journalEntry(3, "labels", "bug"), journalEntry(2, "labels", "bug"), journalEntry(3, "milestone")},
+			calls:   []string{"ReplaceLabels wercker/foo#2 bug"},
+			kept:    []string{"wercker/foo#3 labels -> [bug]", "wercker/foo#3 milestone -> Someday"},
+		},
+	}
+	for _, test := range tests {
+		api := testJournalAPI()
+		journal := &Journal{Entries: test.entries}
+		conflicts := journal.Replay(api, test.force)
+
+		if calls := strings.Join(api.Calls, "; "); calls != strings.Join(test.calls, "; ") {
+			t.Errorf("%s: got calls %q, expected %q", test.name, calls, strings.Join(test.calls, "; "))
+		}
+		if conflicts != test.conflicts {
+			t.Errorf("%s: got %d conflicts, expected %d", test.name, conflicts, test.conflicts)
+		}
+		kept := []string{}
+		for _, entry := range journal.Entries {
+			kept = append(kept, entry.String())
+		}
+		if strings.Join(kept, "; ") != strings.Join(test.kept, "; ") {
+			t.Errorf("%s: kept %q, expected %q", test.name, strings.Join(kept, "; "), strings.Join(test.kept, "; "))
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Change is something set-labels or set-milestones is going to do to a
+// project, so we can show it before doing it
+type Change struct {
+	Action string // create, update, rename or delete
+	Kind   string // label or milestone
+	Name   string
+	// From and To describe it before and after, empty if there's nothing
+	// to say
+	From string
+	To   string
+
+	apply func() error
+}
+
+// String describes the change for a plan
+func (c *Change) String() string {
+	switch c.Action {
+	case "create":
+		if c.To == "" {
+			return fmt.Sprintf("create %s %s", c.Kind, c.Name)
+		}
+		return fmt.Sprintf("create %s %s (%s)", c.Kind, c.Name, c.To)
+	case "update":
+		return fmt.Sprintf("update %s %s: %s -> %s", c.Kind, c.Name, c.From, c.To)
+	case "rename":
+		return fmt.Sprintf("rename %s %s -> %s", c.Kind, c.Name, c.To)
+	case "delete":
+		return fmt.Sprintf("delete %s %s", c.Kind, c.Name)
+	}
+	return fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Name)
+}
+
+// Diff lines for the change
+func (c *Change) Diff() []string {
+	line := func(prefix, value string) string {
+		return strings.TrimSpace(fmt.Sprintf("%s%s %s %s", prefix, c.Kind, c.Name, value))
+	}
+	switch c.Action {
+	case "create":
+		return []string{line("+", c.To)}
+	case "update":
+		return []string{line("-", c.From), line("+", c.To)}
+	case "rename":
+		return []string{line("-", ""), fmt.Sprintf("+%s %s", c.Kind, c.To)}
+	case "delete":
+		return []string{line("-", c.From)}
+	}
+	return []string{fmt.Sprintf("# %s", c)}
+}
+
+// Apply the change
+func (c *Change) Apply() error {
+	logger.Debugln(" ", c)
+	return c.apply()
+}
+
+// Plan is the changes for a single project
+type Plan struct {
+	Project string
+	Changes []*Change
+}
+
+// Add a change to the plan
+func (p *Plan) Add(change *Change) {
+	p.Changes = append(p.Changes, change)
+}
+
+// Print the plan as a list of changes or as a diff
+func (p *Plan) Print(diff bool) {
+	p.Fprint(os.Stdout, diff)
+}
+
+// Fprint the plan to out
+func (p *Plan) Fprint(out io.Writer, diff bool) {
+	if diff {
+		if len(p.Changes) == 0 {
+			return
+		}
+		fmt.Fprintf(out, "--- %s\n+++ %s\n", p.Project, p.Project)
+		for _, change := range p.Changes {
+			for _, line := range change.Diff() {
+				fmt.Fprintln(out, line)
+			}
+		}
+		return
+	}
+
+	fmt.Fprintf(out, "%s:\n", p.Project)
+	if len(p.Changes) == 0 {
+		fmt.Fprintln(out, "  nothing to do")
+	}
+	for _, change := range p.Changes {
+		fmt.Fprintf(out, "  %s\n", change)
+	}
+}
+
+// Apply each change in order, stopping at the first failure
+func (p *Plan) Apply() error {
+	logger.Debugln("Applying plan for:", p.Project)
+	for _, change := range p.Changes {
+		if err := change.Apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
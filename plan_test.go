@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestChangeDiff(t *testing.T) {
+	tests := []struct {
+		change *Change
+		str    string
+		diff   []string
+	}{
+		{&Change{Action: "create", Kind: "milestone", Name: "Next"}, "create milestone Next", []string{"+milestone Next"}},
+		{&Change{Action: "create", Kind: "label", Name: "bug", To: "ee0701"}, "create label bug (ee0701)", []string{"+label bug ee0701"}},
+		{&Change{Action: "update", Kind: "label", Name: "bug", From: "ee0701", To: "fc2929"}, "update label bug: ee0701 -> fc2929", []string{"-label bug ee0701", "+label bug fc2929"}},
+		{&Change{Action: "rename", Kind: "label", Name: "defect", To: "bug"}, "rename label defect -> bug", []string{"-label defect", "+label bug"}},
+		{&Change{Action: "delete", Kind: "label", Name: "wontfix", From: "ffffff"}, "delete label wontfix", []string{"-label wontfix ffffff"}},
+		{&Change{Action: "frob", Kind: "label", Name: "bug"}, "frob label bug", []string{"# frob label bug"}},
+	}
+	for _, test := range tests {
+		if s := test.change.String(); s != test.str {
+			t.Errorf("expected %q, got %q", test.str, s)
+		}
+		if diff := test.change.Diff(); strings.Join(diff, "\n") != strings.Join(test.diff, "\n") {
+			t.Errorf("%s: expected diff %q, got %q", test.str, test.diff, diff)
+		}
+	}
+}
+
+func TestPlanPrint(t *testing.T) {
+	plan := &Plan{Project: "wercker/foo"}
+	plan.Add(&Change{Action: "create", Kind: "milestone", Name: "Next"})
+	plan.Add(&Change{Action: "update", Kind: "milestone", Name: "Someday", From: "closed", To: "open"})
+	empty := &Plan{Project: "wercker/bar"}
+
+	tests := []struct {
+		plan     *Plan
+		diff     bool
+		expected string
+	}{
+		{plan, false, "wercker/foo:\n  create milestone Next\n  update milestone Someday: closed -> open\n"},
+		{plan, true, "--- wercker/foo\n+++ wercker/foo\n+milestone Next\n-milestone Someday closed\n+milestone Someday open\n"},
+		{empty, false, "wercker/bar:\n  nothing to do\n"},
+		{empty, true, ""},
+	}
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		test.plan.Fprint(out, test.diff)
+		if out.String() != test.expected {
+			t.Errorf("%s (diff %v): expected %q, got %q", test.plan.Project, test.diff, test.expected, out.String())
+		}
+	}
+}
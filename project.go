@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 
@@ -31,11 +32,20 @@ var (
 )
 
 func cmdShowProjects(opts *Options, target string) error {
-	tc := AuthClient(opts)
-	client := github.NewClient(tc)
+	config, err := LoadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	// an org on another host looks like ghe.corp/team
+	host, org := splitProject(target + "/")
+	target = strings.TrimSuffix(org, "/")
+	client, err := NewGithubClients(opts, config).Host(host)
+	if err != nil {
+		return err
+	}
 
 	var repos []github.Repository
-	var err error
 
 	// if we specified an org we need to do a different search
 	if target != "" {
@@ -63,7 +73,11 @@ func cmdShowProjects(opts *Options, target string) error {
 	out := []string{}
 	for _, repo := range repos {
 		owner := *repo.Owner
-		out = append(out, fmt.Sprintf("%s/%s", *owner.Login, *repo.Name))
+		project := fmt.Sprintf("%s/%s", *owner.Login, *repo.Name)
+		if host != "" {
+			project = fmt.Sprintf("%s/%s", host, project)
+		}
+		out = append(out, project)
 	}
 
 	d, err := yaml.Marshal(out)
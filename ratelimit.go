@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is the most recent quota the api told us about
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+	// WaitingUntil is set while we're sitting out a rate limit
+	WaitingUntil time.Time
+
+	lock sync.Mutex
+}
+
+// RateLimits keeps a RateLimit for each host and resource, the search api
+// has a much smaller quota than the core one and every GitHub Enterprise
+// host counts separately
+type RateLimits struct {
+	limits map[string]*RateLimit
+	lock   sync.Mutex
+}
+
+// NewRateLimits constructor
+func NewRateLimits() *RateLimits {
+	return &RateLimits{limits: map[string]*RateLimit{}}
+}
+
+// githubRates are shared by every github client we make
+var githubRates = NewRateLimits()
+
+// rateKey is the host and resource a request counts against, GitHub tells
+// us the resource and we go by the path when it doesn't
+func rateKey(req *http.Request, resp *http.Response) string {
+	resource := ""
+	if resp != nil {
+		resource = resp.Header.Get("X-RateLimit-Resource")
+	}
+	if resource == "" {
+		resource = "core"
+		path := strings.TrimPrefix(req.URL.Path, "/api/v3")
+		if strings.HasPrefix(path, "/search/") {
+			resource = "search"
+		} else if strings.HasPrefix(path, "/graphql") {
+			resource = "graphql"
+		}
+	}
+	return fmt.Sprintf("%s %s", req.URL.Host, resource)
+}
+
+// For the limit a response counts against
+func (r *RateLimits) For(req *http.Request, resp *http.Response) *RateLimit {
+	key := rateKey(req, resp)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	limit, ok := r.limits[key]
+	if !ok {
+		limit = &RateLimit{}
+		r.limits[key] = limit
+	}
+	return limit
+}
+
+// String for the status line, whichever limit we're waiting out or else
+// the one closest to running out, named unless it's the core GitHub one
+func (r *RateLimits) String() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	keys := []string{}
+	for key := range r.limits {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	worst := ""
+	worstLeft := 2.0
+	for _, key := range keys {
+		limit := r.limits[key]
+		limit.lock.Lock()
+		// the fraction left, waiting counts as less than none
+		left := 2.0
+		if !limit.WaitingUntil.IsZero() {
+			left = -1
+		} else if limit.Limit > 0 {
+			left = float64(limit.Remaining) / float64(limit.Limit)
+		}
+		limit.lock.Unlock()
+		if left < worstLeft {
+			worst, worstLeft = key, left
+		}
+	}
+	if worst == "" {
+		return ""
+	}
+	s := r.limits[worst].String()
+	if worst != "api.github.com core" {
+		s = fmt.Sprintf("%s %s", worst, s)
+	}
+	return s
+}
+
+// update from the headers of a response
+func (r *RateLimit) update(resp *http.Response) {
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Limit = limit
+	r.Remaining = remaining
+	r.Reset = time.Unix(reset, 0)
+}
+
+// waiting marks that we're sleeping until t, zero when we're done
+func (r *RateLimit) waiting(t time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.WaitingUntil = t
+}
+
+// String for the status line, empty if we haven't heard anything yet
+func (r *RateLimit) String() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.WaitingUntil.IsZero() {
+		return fmt.Sprintf("rate limited until %s", r.WaitingUntil.Format("15:04:05"))
+	}
+	if r.Limit == 0 {
+		return ""
+	}
+	return fmt.Sprintf("api %d/%d", r.Remaining, r.Limit)
+}
+
+// RetryTransport waits out rate limits and retries transient server
+// errors with backoff
+type RetryTransport struct {
+	Base    http.RoundTripper
+	Rates   *RateLimits
+	Retries int
+	Backoff time.Duration
+	// MaxWait is the most we'll sit out rate limits for, in total, before
+	// handing back the 403 or 429
+	MaxWait time.Duration
+}
+
+// NewRetryTransport with our default of 4 retries starting at 1s, and
+// waiting out rate limits for up to an hour, which covers a primary reset
+func NewRetryTransport(base http.RoundTripper, rates *RateLimits) *RetryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RetryTransport{Base: base, Rates: rates, Retries: 4, Backoff: 1 * time.Second, MaxWait: 1 * time.Hour}
+}
+
+// RoundTrip the request, retrying as needed
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// hang on to the body so we can send it again
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	backoff := t.Backoff
+	waited := time.Duration(0)
+	for attempt := 0; ; attempt++ {
+		r := new(http.Request)
+		*r = *req
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := t.Base.RoundTrip(r)
+		last := attempt >= t.Retries
+
+		if err != nil {
+			if last || !retryable(req) {
+				return nil, err
+			}
+			logger.Warnln("Retrying after error:", req.Method, req.URL, err)
+			select {
+			case <-time.After(backoff):
+			case <-req.Cancel:
+				return nil, fmt.Errorf("Canceled while waiting to retry: %s %s", req.Method, req.URL)
+			}
+			backoff *= 2
+			continue
+		}
+
+		var rate *RateLimit
+		if t.Rates != nil {
+			rate = t.Rates.For(req, resp)
+			rate.update(resp)
+		}
+
+		wait := time.Duration(0)
+		switch {
+		case resp.StatusCode == 403 || resp.StatusCode == 429:
+			// secondary rate limits tell us how long to wait, the primary
+			// one tells us when it resets
+			if after, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(after) * time.Second
+			} else if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+				reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+				wait = time.Unix(reset, 0).Sub(time.Now()) + 1*time.Second
+			} else {
+				return resp, nil
+			}
+			if wait < 0 {
+				wait = 0
+			}
+			if last || waited+wait > t.MaxWait {
+				logger.Warnf("Giving up on %s %s after waiting %s (%s)", req.Method, req.URL, waited, resp.Status)
+				return resp, nil
+			}
+			waited += wait
+		case resp.StatusCode >= 500 && resp.StatusCode != 501 && retryable(req):
+			if last {
+				return resp, nil
+			}
+			wait = backoff
+			backoff *= 2
+		default:
+			return resp, nil
+		}
+
+		logger.Warnf("Waiting %s to retry %s %s (%s)", wait, req.Method, req.URL, resp.Status)
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		if rate != nil {
+			rate.waiting(time.Now().Add(wait))
+		}
+		canceled := false
+		select {
+		case <-time.After(wait):
+		case <-req.Cancel:
+			canceled = true
+		}
+		if rate != nil {
+			rate.waiting(time.Time{})
+		}
+		if canceled {
+			return nil, fmt.Errorf("Canceled while waiting to retry: %s %s", req.Method, req.URL)
+		}
+	}
+}
+
+// retryable requests are the ones that are safe to send twice, a POST may
+// have worked even if we got an error back
+func retryable(req *http.Request) bool {
+	return req.Method != "POST"
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer answers each request with the next of the statuses, the
+// last one over and over, and counts the requests
+type testServer struct {
+	statuses []int
+	headers  http.Header
+	requests int
+	lock     sync.Mutex
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	status := s.statuses[len(s.statuses)-1]
+	if s.requests < len(s.statuses) {
+		status = s.statuses[s.requests]
+	}
+	s.requests++
+	s.lock.Unlock()
+	for key, values := range s.headers {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(status)
+}
+
+// testTransport retries quickly
+func testTransport() *RetryTransport {
+	t := NewRetryTransport(nil, NewRateLimits())
+	t.Backoff = 1 * time.Millisecond
+	return t
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		headers  http.Header
+		maxWait  time.Duration
+		status   int
+		requests int
+	}{
+		{"ok", "GET", []int{200}, nil, time.Hour, 200, 1},
+		{"retry after", "GET", []int{429, 200}, http.Header{"Retry-After": {"0"}}, time.Hour, 200, 2},
+		{"primary reset", "GET", []int{403, 200}, http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"0"}}, time.Hour, 200, 2},
+		{"plain forbidden", "GET", []int{403, 200}, nil, time.Hour, 403, 1},
+		{"over max wait", "GET", []int{429, 200}, http.Header{"Retry-After": {"5"}}, time.Second, 429, 1},
+		{"server errors back off", "GET", []int{502, 503, 200}, nil, time.Hour, 200, 3},
+		{"server errors run out", "GET", []int{500}, nil, time.Hour, 500, 5},
+		{"not implemented", "GET", []int{501, 200}, nil, time.Hour, 501, 1},
+		{"posts aren't resent", "POST", []int{502, 200}, nil, time.Hour, 502, 1},
+	}
+	for _, test := range tests {
+		server := &testServer{statuses: test.statuses, headers: test.headers}
+		ts := httptest.NewServer(server)
+		transport := testTransport()
+		transport.MaxWait = test.maxWait
+
+		req, _ := http.NewRequest(test.method, ts.URL, strings.NewReader("body"))
+		resp, err := transport.RoundTrip(req)
+		ts.Close()
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status || server.requests != test.requests {
+			t.Errorf("%s: got %d after %d requests, expected %d after %d", test.name, resp.StatusCode, server.requests, test.status, test.requests)
+		}
+	}
+}
+
+// failingTransport never gets through
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("connection refused")
+}
+
+func TestRetryTransportCancel(t *testing.T) {
+	server := &testServer{statuses: []int{429}, headers: http.Header{"Retry-After": {"60"}}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	slow := testTransport()
+	errors := NewRetryTransport(failingTransport{}, nil)
+	errors.Backoff = 1 * time.Hour
+	for _, transport := range []*RetryTransport{slow, errors} {
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		cancel := make(chan struct{})
+		req.Cancel = cancel
+		time.AfterFunc(10*time.Millisecond, func() { close(cancel) })
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := transport.RoundTrip(req)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err == nil || !strings.HasPrefix(err.Error(), "Canceled") {
+				t.Errorf("expected it to be canceled, got: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("still waiting after being canceled")
+		}
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	rates := NewRateLimits()
+	if s := rates.String(); s != "" {
+		t.Errorf("expected nothing before any requests, got %q", s)
+	}
+
+	update := func(url string, headers map[string]string) {
+		req, _ := http.NewRequest("GET", url, nil)
+		resp := &http.Response{Header: http.Header{}}
+		for key, value := range headers {
+			resp.Header.Set(key, value)
+		}
+		rates.For(req, resp).update(resp)
+	}
+	update("https://api.github.com/repos/wercker/foo/issues", map[string]string{"X-RateLimit-Limit": "5000", "X-RateLimit-Remaining": "4000"})
+	if s := rates.String(); s != "api 4000/5000" {
+		t.Errorf("wrong status: %q", s)
+	}
+	// search has its own, smaller quota whether or not it says so
+	update("https://api.github.com/search/issues", map[string]string{"X-RateLimit-Limit": "30", "X-RateLimit-Remaining": "29"})
+	if s := rates.String(); s != "api 4000/5000" {
+		t.Errorf("wrong status: %q", s)
+	}
+	update("https://ghe.corp/api/v3/search/issues", map[string]string{"X-RateLimit-Limit": "30", "X-RateLimit-Remaining": "3", "X-RateLimit-Resource": "search"})
+	if s := rates.String(); s != "ghe.corp search api 3/30" {
+		t.Errorf("wrong status: %q", s)
+	}
+	update("https://api.github.com/search/issues", map[string]string{"X-RateLimit-Limit": "30", "X-RateLimit-Remaining": "0"})
+	if s := rates.String(); s != "api.github.com search api 0/30" {
+		t.Errorf("wrong status: %q", s)
+	}
+	if len(rates.limits) != 3 {
+		t.Errorf("expected 3 limits, got %d", len(rates.limits))
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/google/go-github/github"
+)
+
+var (
+	rolloverCommand = cli.Command{
+		Name:      "rollover",
+		Usage:     "move open issues from past due milestones into the current one",
+		ArgsUsage: "[project]",
+		Action: func(c *cli.Context) {
+			opts, err := NewOptions(c)
+			if err != nil {
+				logger.Errorln("Invalid options", err)
+				os.Exit(1)
+			}
+			target := c.Args().First()
+			if target == "" {
+				target = "all"
+			}
+			err = cmdRollover(opts, target, c.String("to"), c.Bool("close"))
+			if err != nil {
+				SoftExit(opts, err)
+			}
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "to", Value: "current", Usage: "the milestone tier to move them to, like current or next"},
+			cli.BoolFlag{Name: "close", Usage: "close the old milestones once they're empty"},
+		},
+	}
+)
+
+// pastDue are the milestones with a due date before now
+func pastDue(milestones []*Milestone, now time.Time) []*Milestone {
+	out := []*Milestone{}
+	for _, m := range milestones {
+		if m.DueOn != nil && m.DueOn.Before(now) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// cmdRollover moves the open issues in each project's past due milestones
+// into the Current (or another tier's) milestone
+func cmdRollover(opts *Options, target, to string, closeOld bool) error {
+	config, err := LoadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	tier := -1
+	for i, t := range config.MilestoneTiers {
+		if strings.EqualFold(t.Name, to) {
+			tier = i
+		}
+	}
+	if tier < 0 {
+		return fmt.Errorf("Can only roll over to a milestone tier, not: %s", to)
+	}
+	api, err := NewAPI(opts, config)
+	if err != nil {
+		return err
+	}
+
+	var projects []string
+	if target == "all" {
+		projects = config.AllProjects()
+	} else {
+		projects = strings.Split(target, " ")
+	}
+
+	failed := 0
+	for _, project := range projects {
+		fmt.Printf("%s:\n", project)
+		err := rolloverProject(api, config, project, tier, closeOld)
+		if err != nil {
+			fmt.Printf("  failed: %s\n", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("Rollover failed for %d of %d projects", failed, len(projects))
+	}
+	return nil
+}
+
+// rolloverProject does the rollover for a single project and prints what
+// it did, the issues are moved through the api like the ui moves them
+func rolloverProject(api API, config *Config, project string, tier int, closeOld bool) error {
+	theirs, err := api.OpenMilestones(project)
+	if err != nil {
+		return err
+	}
+
+	// a project missing some other tier can still roll over to this one,
+	// which is the only time we get tiers back along with an error
+	tiers, err := api.Milestones(project)
+	if err != nil && tiers == nil {
+		return err
+	}
+	var dest *Milestone
+	if tier < len(tiers) {
+		dest = tiers[tier]
+	}
+	if dest == nil {
+		return fmt.Errorf("No milestone to roll over to, try create-milestone")
+	}
+
+	old := pastDue(theirs, time.Now())
+	if len(old) == 0 {
+		fmt.Printf("  nothing past due\n")
+		return nil
+	}
+
+	failed := 0
+	for _, m := range old {
+		issues, err := api.MilestoneIssues(project, m)
+		if err != nil {
+			return err
+		}
+
+		moved := 0
+		for _, remote := range issues {
+			issue := NewIssue(remote, nil, config.MilestoneTiers, config.Dimensions)
+			issue.Project = project
+			err := api.SetMilestone(issue, dest)
+			if err != nil {
+				fmt.Printf("  failed: %s#%d: %s\n", project, issue.Number, err)
+				continue
+			}
+			moved++
+		}
+
+		summary := fmt.Sprintf("  %s: moved %d of %d open issues to %s", m.Title, moved, len(issues), dest.Title)
+		if moved < len(issues) {
+			failed++
+		} else if closeOld {
+			err := api.CloseMilestone(project, m)
+			if err != nil {
+				return err
+			}
+			summary += ", closed it"
+		}
+		fmt.Println(summary)
+	}
+	if failed > 0 {
+		return fmt.Errorf("Some issues couldn't be moved")
+	}
+	return nil
+}
+
+// milestoneIssues are all the open issues in a milestone
+func milestoneIssues(client *github.Client, owner, repo string, number int) ([]github.Issue, error) {
+	opt := &github.IssueListByRepoOptions{
+		Milestone:   fmt.Sprintf("%d", number),
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	issues := []github.Issue{}
+	for {
+		page, resp, err := client.Issues.ListByRepo(owner, repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, page...)
+		if resp.NextPage == 0 {
+			return issues, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
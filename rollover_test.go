@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestRolloverProject(t *testing.T) {
+	config := testConfig()
+	past := time.Now().AddDate(0, 0, -3)
+	future := time.Now().AddDate(0, 0, 4)
+	sprint := &github.Milestone{Number: github.Int(9), Title: github.String("Sprint 1"), DueOn: &past}
+	issue := func(project string, number int, state string) github.Issue {
+		return github.Issue{
+			Number:    github.Int(number),
+			Title:     github.String("Thing"),
+			State:     github.String(state),
+			HTMLURL:   github.String(fmt.Sprintf("https://github.com/%s/issues/%d", project, number)),
+			Milestone: sprint,
+		}
+	}
+	current := &Milestone{Number: 1, Title: "Sprint 2", DueOn: &future}
+	someday := &Milestone{Number: 3, Title: "Someday"}
+
+	tests := []struct {
+		project  string
+		closeOld bool
+		err      bool
+		calls    string
+	}{
+		{"wercker/foo", false, false, "SetMilestone wercker/foo#1 1; SetMilestone wercker/foo#2 1"},
+		{"wercker/foo", true, false, "SetMilestone wercker/foo#1 1; SetMilestone wercker/foo#2 1; CloseMilestone wercker/foo 9"},
+		// missing the Next tier doesn't stop it rolling over to Current
+		{"wercker/bar", true, false, "SetMilestone wercker/bar#4 1; CloseMilestone wercker/bar 9"},
+		{"wercker/baz", true, true, ""},
+	}
+	for _, test := range tests {
+		api := NewFakeAPI([]github.Issue{
+			issue("wercker/foo", 1, "open"),
+			issue("wercker/foo", 2, "open"),
+			issue("wercker/foo", 3, "closed"),
+			issue("wercker/bar", 4, "open"),
+			issue("wercker/baz", 5, "open"),
+		}, map[string][]*Milestone{
+			"wercker/foo": {current, {Number: 2, Title: "Sprint 3"}, someday},
+			"wercker/bar": {current, nil, someday},
+		})
+		err := rolloverProject(api, config, test.project, 0, test.closeOld)
+		if (err != nil) != test.err {
+			t.Errorf("%s: unexpected error: %v", test.project, err)
+		}
+		if calls := strings.Join(api.Calls, "; "); calls != test.calls {
+			t.Errorf("%s: expected %q, got %q", test.project, test.calls, calls)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Screen is the part of termbox we draw with, so that we can draw into
+// memory instead of a terminal
+type Screen interface {
+	SetCell(x, y int, ch rune, fg, bg termbox.Attribute)
+	CellBuffer() []termbox.Cell
+	Size() (int, int)
+	Clear(fg, bg termbox.Attribute) error
+	Flush() error
+	SetCursor(x, y int)
+	HideCursor()
+	SetOutputMode(termbox.OutputMode) termbox.OutputMode
+	// Suspend gives the terminal to f, for running an editor
+	Suspend(f func() error) error
+}
+
+// screen is where all the drawing goes, termbox unless told otherwise
+var screen Screen = termboxScreen{}
+
+// termboxScreen passes everything through to termbox
+type termboxScreen struct{}
+
+func (termboxScreen) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	termbox.SetCell(x, y, ch, fg, bg)
+}
+
+func (termboxScreen) CellBuffer() []termbox.Cell {
+	return termbox.CellBuffer()
+}
+
+func (termboxScreen) Size() (int, int) {
+	return termbox.Size()
+}
+
+func (termboxScreen) Clear(fg, bg termbox.Attribute) error {
+	return termbox.Clear(fg, bg)
+}
+
+func (termboxScreen) Flush() error {
+	return termbox.Flush()
+}
+
+func (termboxScreen) SetCursor(x, y int) {
+	termbox.SetCursor(x, y)
+}
+
+func (termboxScreen) HideCursor() {
+	termbox.HideCursor()
+}
+
+func (termboxScreen) SetOutputMode(mode termbox.OutputMode) termbox.OutputMode {
+	return termbox.SetOutputMode(mode)
+}
+
+func (termboxScreen) Suspend(f func() error) error {
+	termbox.Close()
+	err := f()
+	if initErr := termbox.Init(); initErr != nil {
+		return initErr
+	}
+	termbox.SetOutputMode(termbox.Output256)
+	return err
+}
+
+// CellScreen is an in-memory Screen, handy for checking what got drawn
+type CellScreen struct {
+	width   int
+	height  int
+	cells   []termbox.Cell
+	CursorX int
+	CursorY int
+}
+
+// NewCellScreen ctor
+func NewCellScreen(width, height int) *CellScreen {
+	s := &CellScreen{width: width, height: height, CursorX: -1, CursorY: -1}
+	s.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	return s
+}
+
+// SetCell like termbox, anything off the screen is dropped
+func (s *CellScreen) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	if x < 0 || x >= s.width || y < 0 || y >= s.height {
+		return
+	}
+	s.cells[y*s.width+x] = termbox.Cell{Ch: ch, Fg: fg, Bg: bg}
+}
+
+// CellBuffer is the live buffer, like termbox
+func (s *CellScreen) CellBuffer() []termbox.Cell {
+	return s.cells
+}
+
+// Size of the screen
+func (s *CellScreen) Size() (int, int) {
+	return s.width, s.height
+}
+
+// Clear fills the screen with blanks
+func (s *CellScreen) Clear(fg, bg termbox.Attribute) error {
+	s.cells = make([]termbox.Cell, s.width*s.height)
+	for i := range s.cells {
+		s.cells[i] = termbox.Cell{Ch: ' ', Fg: fg, Bg: bg}
+	}
+	return nil
+}
+
+// Flush noop
+func (s *CellScreen) Flush() error {
+	return nil
+}
+
+// SetCursor remembers where the cursor is
+func (s *CellScreen) SetCursor(x, y int) {
+	s.CursorX = x
+	s.CursorY = y
+}
+
+// HideCursor like termbox uses -1 for hidden
+func (s *CellScreen) HideCursor() {
+	s.SetCursor(-1, -1)
+}
+
+// SetOutputMode noop
+func (s *CellScreen) SetOutputMode(mode termbox.OutputMode) termbox.OutputMode {
+	return mode
+}
+
+// Suspend just runs f, there's no terminal to give up
+func (s *CellScreen) Suspend(f func() error) error {
+	return f()
+}
+
+// String renders the characters on the screen, one line per row with
+// trailing spaces trimmed, colors are ignored
+func (s *CellScreen) String() string {
+	lines := []string{}
+	for y := 0; y < s.height; y++ {
+		row := make([]rune, s.width)
+		for x := 0; x < s.width; x++ {
+			row[x] = s.cells[y*s.width+x].Ch
+			if row[x] == 0 {
+				row[x] = ' '
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(row), " "))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	setCommand = cli.Command{
+		Name:      "set",
+		Usage:     "set the milestone, priority, type or other labels of issues",
+		ArgsUsage: "[owner/repo#123 ...] (or - to read them from stdin)",
+		Action: func(c *cli.Context) {
+			opts, err := NewOptions(c)
+			if err != nil {
+				logger.Errorln("Invalid options", err)
+				os.Exit(1)
+			}
+			refs := []string(c.Args())
+			if len(refs) == 0 || (len(refs) == 1 && refs[0] == "-") {
+				refs, err = readRefs(os.Stdin)
+				if err != nil {
+					SoftExit(opts, err)
+				}
+			}
+			labels, err := parseLabelFlags(c.StringSlice("label"))
+			if err != nil {
+				SoftExit(opts, err)
+			}
+			if priority := c.String("priority"); priority != "" {
+				labels["priority"] = priority
+			}
+			if typ := c.String("type"); typ != "" {
+				labels["type"] = typ
+			}
+			err = cmdSet(opts, refs, c.String("milestone"), labels)
+			if err != nil {
+				SoftExit(opts, err)
+			}
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "milestone", Usage: "a milestone tier like current, next or someday, or none"},
+			cli.StringFlag{Name: "priority", Usage: "name or number of a priority, or none"},
+			cli.StringFlag{Name: "type", Usage: "name or number of a type, or none"},
+			cli.StringSliceFlag{Name: "label", Value: &cli.StringSlice{}, Usage: "dimension=label for any dimension, e.g. size=large"},
+		},
+	}
+)
+
+// readRefs reads whitespace separated issue refs
+func readRefs(in io.Reader) ([]string, error) {
+	refs := []string{}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		refs = append(refs, strings.Fields(scanner.Text())...)
+	}
+	return refs, scanner.Err()
+}
+
+// parseLabelFlags turns dimension=label pairs into a map
+func parseLabelFlags(flags []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, flag := range flags {
+		parts := strings.SplitN(flag, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Expected a label like dimension=label, got: %s", flag)
+		}
+		labels[strings.ToLower(parts[0])] = parts[1]
+	}
+	return labels, nil
+}
+
+// parseIssueRef turns owner/repo#123, host/owner/repo#123 or an issue url
+// into enough of an Issue to Get from an API
+func parseIssueRef(config *Config, ref string) (*Issue, error) {
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+		owner, repo, err := ownerRepoFromURL(ref)
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(strings.TrimRight(ref, "/"), "/")
+		number, err := strconv.Atoi(parts[len(parts)-1])
+		if err != nil {
+			return nil, fmt.Errorf("Not an issue url: %s", ref)
+		}
+		project := fmt.Sprintf("%s/%s", owner, repo)
+		// anywhere but the default github the project needs its host to
+		// find the right milestones, unless it's one of our gitlab ones
+		host := hostFromURL(ref)
+		if host != config.GithubHost() && !(host == hostFromURL(config.Gitlab.URL) && config.IsGitlabProject(project)) {
+			project = fmt.Sprintf("%s/%s", host, project)
+		}
+		return &Issue{Number: number, URL: ref, Owner: owner, Repo: repo, Project: project}, nil
+	}
+
+	i := strings.LastIndex(ref, "#")
+	if i < 0 {
+		return nil, fmt.Errorf("Expected an issue like owner/repo#123, got: %s", ref)
+	}
+	project := ref[:i]
+	number, err := strconv.Atoi(ref[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("Expected an issue like owner/repo#123, got: %s", ref)
+	}
+
+	host, path := splitProject(project)
+	var url string
+	if config.IsGitlabProject(project) {
+		url = fmt.Sprintf("%s/%s/-/issues/%d", strings.TrimRight(config.Gitlab.URL, "/"), project, number)
+	}
+	if url == "" {
+		if host == "" {
+			host = config.GithubHost()
+		}
+		if _, _, err := ownerRepo(path); err != nil {
+			return nil, err
+		}
+		url = fmt.Sprintf("https://%s/%s/issues/%d", host, path, number)
+	}
+
+	owner, repo, err := ownerRepoFromURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &Issue{Number: number, URL: url, Owner: owner, Repo: repo, Project: project}, nil
+}
+
+// resolveMilestone picks one of our milestones by tier name or hotkey,
+// its title, or none, returning the index the ui would show
+func resolveMilestone(config *Config, milestones []*Milestone, s string) (int, *Milestone, error) {
+	if strings.ToLower(s) == "none" || s == "0" {
+		return 0, nil, nil
+	}
+	i := -1
+	for j, tier := range config.MilestoneTiers {
+		if strings.EqualFold(tier.Name, s) || tier.Hotkey == s || (!tier.Dated && strings.EqualFold(tier.Title, s)) {
+			i = j
+		}
+	}
+	if i < 0 {
+		for j, m := range milestones {
+			if m != nil && strings.EqualFold(m.Title, s) {
+				i = j
+			}
+		}
+	}
+	if i < 0 {
+		return 0, nil, fmt.Errorf("Unknown milestone: %s", s)
+	}
+	if i >= len(milestones) || milestones[i] == nil {
+		return 0, nil, fmt.Errorf("No %s milestone found", s)
+	}
+	return tierIndex(config.MilestoneTiers, i), milestones[i], nil
+}
+
+// resolveLabel picks one of labels by name, alias or number, returning
+// the index the ui would show, 0 being none
+func resolveLabel(labels []Label, s string) (int, error) {
+	if strings.ToLower(s) == "none" {
+		return 0, nil
+	}
+	if i, err := strconv.Atoi(s); err == nil && i >= 0 && i <= len(labels) {
+		return i, nil
+	}
+	for i, label := range labels {
+		if label.Matches(s) {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown label: %s", s)
+}
+
+// cmdSet applies a milestone and labels by dimension to each issue,
+// carrying on past failures and reporting them at the end
+func cmdSet(opts *Options, refs []string, milestone string, labels map[string]string) error {
+	if len(refs) == 0 {
+		return fmt.Errorf("No issues given")
+	}
+	if milestone == "" && len(labels) == 0 {
+		return fmt.Errorf("Nothing to set, use --milestone, --priority, --type or --label")
+	}
+
+	config, err := LoadConfig(opts)
+	if err != nil {
+		return err
+	}
+	api, err := NewAPI(opts, config)
+	if err != nil {
+		return err
+	}
+
+	return setIssues(api, config, refs, milestone, labels)
+}
+
+// setIssues does the work of cmdSet against an API
+func setIssues(api API, config *Config, refs []string, milestone string, labels map[string]string) error {
+	// check these up front so a typo doesn't get halfway through, -1
+	// leaves a dimension alone
+	indexes := make([]int, len(config.Dimensions))
+	for i := range indexes {
+		indexes[i] = -1
+	}
+	for name, label := range labels {
+		found := false
+		for i, d := range config.Dimensions {
+			if !strings.EqualFold(d.Name, name) {
+				continue
+			}
+			index, err := resolveLabel(d.Labels, label)
+			if err != nil {
+				return err
+			}
+			indexes[i] = index
+			found = true
+		}
+		if !found {
+			return fmt.Errorf("Unknown dimension: %s", name)
+		}
+	}
+
+	milestones := map[string][]*Milestone{}
+	failed := 0
+	for _, ref := range refs {
+		err := setIssue(api, config, milestones, ref, milestone, indexes)
+		if err != nil {
+			fmt.Printf("  failed: %s: %s\n", ref, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d issues not set", failed, len(refs))
+	}
+	return nil
+}
+
+// setIssue sets the milestone (if not empty) and the label of each
+// dimension (if its index isn't -1) on a single issue
+func setIssue(api API, config *Config, milestones map[string][]*Milestone, ref, milestone string, indexes []int) error {
+	ours, err := parseIssueRef(config, ref)
+	if err != nil {
+		return err
+	}
+
+	// milestones are looked up once per project, and only if we need them
+	clearing := strings.ToLower(milestone) == "none" || milestone == "0"
+	if _, ok := milestones[ours.Project]; !ok && milestone != "" && !clearing {
+		ms, err := api.Milestones(ours.Project)
+		if err != nil && ms == nil {
+			return err
+		}
+		milestones[ours.Project] = ms
+	}
+
+	remote, err := api.Get(ours)
+	if err != nil {
+		return err
+	}
+	issue := NewIssue(*remote, milestones, config.MilestoneTiers, config.Dimensions)
+	issue.Project = ours.Project
+
+	changes := []string{}
+	if milestone != "" {
+		_, m, err := resolveMilestone(config, milestones[ours.Project], milestone)
+		if err != nil {
+			return err
+		}
+		err = api.SetMilestone(issue, m)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, fmt.Sprintf("milestone -> %s", milestoneTitle(m)))
+	}
+
+	labels := issue.Labels
+	changed := false
+	for i, d := range config.Dimensions {
+		if indexes[i] < 0 {
+			continue
+		}
+		label := ""
+		if indexes[i] > 0 {
+			label = labelFor(labels, d.Labels[indexes[i]-1], config.ShouldNormalizeAliases())
+		}
+		labels = swapLabel(labels, d.Labels, label)
+		changes = append(changes, fmt.Sprintf("%s -> %s", d.Name, noneIfEmpty(label)))
+		changed = true
+	}
+	if changed {
+		err = api.ReplaceLabels(issue, labels)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("     set: %s: %s\n", ref, strings.Join(changes, ", "))
+	return nil
+}
+
+// noneIfEmpty for display
+func noneIfEmpty(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestParseIssueRef(t *testing.T) {
+	config := testConfig()
+	config.Gitlab = GitlabConfig{URL: "https://gitlab.corp", Projects: Projects{"group/sub/repo"}}
+
+	tests := []struct {
+		ref     string
+		project string
+		url     string
+	}{
+		{"wercker/foo#12", "wercker/foo", "https://github.com/wercker/foo/issues/12"},
+		{"ghe.corp/team/repo#1", "ghe.corp/team/repo", "https://ghe.corp/team/repo/issues/1"},
+		{"group/sub/repo#5", "group/sub/repo", "https://gitlab.corp/group/sub/repo/-/issues/5"},
+		{"https://github.com/wercker/foo/issues/12", "wercker/foo", "https://github.com/wercker/foo/issues/12"},
+		{"https://ghe.corp/team/repo/issues/1", "ghe.corp/team/repo", "https://ghe.corp/team/repo/issues/1"},
+		{"https://gitlab.corp/group/sub/repo/-/issues/5", "group/sub/repo", "https://gitlab.corp/group/sub/repo/-/issues/5"},
+	}
+	for _, test := range tests {
+		issue, err := parseIssueRef(config, test.ref)
+		if err != nil {
+			t.Errorf("%s: %s", test.ref, err)
+			continue
+		}
+		if issue.Project != test.project || issue.URL != test.url {
+			t.Errorf("%s: got %s %s, expected %s %s", test.ref, issue.Project, issue.URL, test.project, test.url)
+		}
+	}
+
+	for _, bad := range []string{"wercker/foo", "wercker/foo#x", "foo#1", "https://github.com/wercker/foo/issues/x"} {
+		if _, err := parseIssueRef(config, bad); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
+
+func TestSetIssuesOnAnotherHost(t *testing.T) {
+	config := testConfig()
+	issue := func(url string) github.Issue {
+		return github.Issue{Number: github.Int(1), Title: github.String("Thing"), HTMLURL: github.String(url)}
+	}
+	// the same owner/repo on both hosts, with different milestones
+	dotcom := NewFakeAPI([]github.Issue{issue("https://github.com/team/repo/issues/1")}, map[string][]*Milestone{
+		"team/repo": {{Number: 1, Title: "Current"}, {Number: 2, Title: "Next"}, {Number: 3, Title: "Someday"}},
+	})
+	ghe := NewFakeAPI([]github.Issue{issue("https://ghe.corp/team/repo/issues/1")}, map[string][]*Milestone{
+		"ghe.corp/team/repo": {{Number: 7, Title: "Current"}, {Number: 8, Title: "Next"}, {Number: 9, Title: "Someday"}},
+	})
+	api := NewMultiAPI(config.GithubHost(), dotcom, config)
+	api.Add("ghe.corp", ghe)
+
+	err := setIssues(api, config, []string{"https://ghe.corp/team/repo/issues/1"}, "next", map[string]string{"priority": "low"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dotcom.Calls) != 0 {
+		t.Errorf("expected nothing on github.com, got: %s", strings.Join(dotcom.Calls, "; "))
+	}
+	expected := "SetMilestone ghe.corp/team/repo#1 8; ReplaceLabels ghe.corp/team/repo#1 low"
+	if calls := strings.Join(ghe.Calls, "; "); calls != expected {
+		t.Errorf("expected %q, got %q", expected, calls)
+	}
+}
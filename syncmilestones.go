@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/google/go-github/github"
+)
+
+var (
+	syncMilestonesCommand = cli.Command{
+		Name:      "sync-milestones",
+		Usage:     "check that projects share the same dated milestones, and fix them",
+		ArgsUsage: "[title]",
+		Action: func(c *cli.Context) {
+			opts, err := NewOptions(c)
+			if err != nil {
+				logger.Errorln("Invalid options", err)
+				os.Exit(1)
+			}
+			title := strings.Join([]string(c.Args()), " ")
+			err = cmdSyncMilestones(opts, title, c.Bool("fix"), c.Bool("diff"))
+			if err != nil {
+				SoftExit(opts, err)
+			}
+		},
+		Flags: []cli.Flag{
+			cli.BoolFlag{Name: "fix", Usage: "create and update milestones so every project matches"},
+			cli.BoolFlag{Name: "diff", Usage: "show what would change as a diff"},
+		},
+	}
+)
+
+// MilestoneGroup is a dated milestone with the same title across projects
+type MilestoneGroup struct {
+	Title string
+	// Due is the due date most of the projects agree on
+	Due time.Time
+	// Projects that have it
+	Projects map[string]github.Milestone
+}
+
+// sharedGroups are the groups at least two projects have, a milestone only
+// one project has is a one-off unless it's asked for by title
+func sharedGroups(groups []*MilestoneGroup) []*MilestoneGroup {
+	shared := []*MilestoneGroup{}
+	for _, group := range groups {
+		if len(group.Projects) > 1 {
+			shared = append(shared, group)
+		}
+	}
+	return shared
+}
+
+// dueDate is what we compare due dates by, github moves the time around
+func dueDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// groupMilestones groups the open milestones due after now by title,
+// soonest first
+func groupMilestones(theirs map[string][]github.Milestone, now time.Time) []*MilestoneGroup {
+	byTitle := map[string]*MilestoneGroup{}
+	for project, milestones := range theirs {
+		for _, m := range milestones {
+			if m.DueOn == nil || !m.DueOn.After(now) || (m.State != nil && *m.State != "open") {
+				continue
+			}
+			group, ok := byTitle[*m.Title]
+			if !ok {
+				group = &MilestoneGroup{Title: *m.Title, Projects: map[string]github.Milestone{}}
+				byTitle[*m.Title] = group
+			}
+			group.Projects[project] = m
+		}
+	}
+
+	groups := []*MilestoneGroup{}
+	for _, group := range byTitle {
+		// the most common date wins, the earliest if it's a tie
+		counts := map[string]int{}
+		projects := []string{}
+		for project, m := range group.Projects {
+			counts[dueDate(*m.DueOn)]++
+			projects = append(projects, project)
+		}
+		sort.Strings(projects)
+		for _, project := range projects {
+			m := group.Projects[project]
+			date := dueDate(*m.DueOn)
+			best := dueDate(group.Due)
+			if group.Due.IsZero() || counts[date] > counts[best] || (counts[date] == counts[best] && date < best) {
+				group.Due = *m.DueOn
+			}
+		}
+		groups = append(groups, group)
+	}
+	sort.Sort(byGroupDue(groups))
+	return groups
+}
+
+// byGroupDue sorts groups by due date, then title
+type byGroupDue []*MilestoneGroup
+
+// Len for Sortable
+func (gs byGroupDue) Len() int {
+	return len(gs)
+}
+
+// Swap for Sortable
+func (gs byGroupDue) Swap(i, j int) {
+	gs[i], gs[j] = gs[j], gs[i]
+}
+
+// Less for Sortable
+func (gs byGroupDue) Less(i, j int) bool {
+	if dueDate(gs[i].Due) == dueDate(gs[j].Due) {
+		return gs[i].Title < gs[j].Title
+	}
+	return gs[i].Due.Before(gs[j].Due)
+}
+
+// shadowing are the open milestones due after now but before due, the
+// nearest of them will be Current rather than one due then
+func shadowing(milestones []github.Milestone, due, now time.Time) []github.Milestone {
+	out := []github.Milestone{}
+	for _, m := range milestones {
+		if m.DueOn == nil || !m.DueOn.After(now) || dueDate(*m.DueOn) >= dueDate(due) {
+			continue
+		}
+		if m.State != nil && *m.State != "open" {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// cmdSyncMilestones reports which projects are missing a dated milestone
+// the others have, or have it due on another day, and fixes them if asked
+func cmdSyncMilestones(opts *Options, title string, fix, diff bool) error {
+	config, err := LoadConfig(opts)
+	if err != nil {
+		return err
+	}
+	clients := NewGithubClients(opts, config)
+
+	// closed and past due ones too, so we can tell those from missing
+	theirs := map[string][]github.Milestone{}
+	projects := []string{}
+	failed := 0
+	for _, project := range config.Projects {
+		client, owner, repo, err := clients.For(project)
+		if err == nil {
+			theirs[project], err = listMilestones(client, owner, repo, "all")
+		}
+		if err != nil {
+			fmt.Printf("%s:\n  failed: %s\n", project, err)
+			failed++
+			continue
+		}
+		projects = append(projects, project)
+	}
+
+	now := time.Now()
+	groups := groupMilestones(theirs, now)
+	if title != "" {
+		found := []*MilestoneGroup{}
+		for _, group := range groups {
+			if group.Title == title {
+				found = append(found, group)
+			}
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("No upcoming milestone called: %s", title)
+		}
+		groups = found
+	} else {
+		groups = sharedGroups(groups)
+	}
+
+	outOfSync := 0
+	for _, project := range projects {
+		client, owner, repo, err := clients.For(project)
+		if err != nil {
+			return err
+		}
+		plan := planMilestoneSync(client, project, owner, repo, theirs[project], groups, now)
+		outOfSync += len(plan.Changes)
+		if !fix {
+			plan.Print(diff)
+			continue
+		}
+		err = plan.Apply()
+		if err != nil {
+			fmt.Printf("%s:\n  failed: %s\n", project, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Sync failed for %d of %d projects", failed, len(config.Projects))
+	}
+	if outOfSync > 0 && !fix {
+		return fmt.Errorf("%d milestones out of sync, use --fix to fix them", outOfSync)
+	}
+	return nil
+}
+
+// planMilestoneSync works out what a project needs for its milestones to
+// match the groups, warning about anything that would be Current instead
+func planMilestoneSync(client *github.Client, project, owner, repo string, theirs []github.Milestone, groups []*MilestoneGroup, now time.Time) *Plan {
+	plan := &Plan{Project: project}
+	for _, group := range groups {
+		group := group
+		m, ok := group.Projects[project]
+		if !ok {
+			// one that's closed or past due can't be created again, so
+			// it's moved instead
+			m, ok = findMilestone(theirs, group.Title)
+		}
+		switch {
+		case !ok:
+			plan.Add(&Change{
+				Action: "create",
+				Kind:   "milestone",
+				Name:   group.Title,
+				To:     fmt.Sprintf("due %s", dueDate(group.Due)),
+				apply: func() error {
+					_, _, err := client.Issues.CreateMilestone(owner, repo, &github.Milestone{Title: &group.Title, DueOn: &group.Due})
+					return err
+				},
+			})
+		case milestoneStatus(m) != fmt.Sprintf("due %s", dueDate(group.Due)):
+			number := *m.Number
+			plan.Add(&Change{
+				Action: "update",
+				Kind:   "milestone",
+				Name:   group.Title,
+				From:   milestoneStatus(m),
+				To:     fmt.Sprintf("due %s", dueDate(group.Due)),
+				apply: func() error {
+					_, _, err := client.Issues.EditMilestone(owner, repo, number, &github.Milestone{DueOn: &group.Due, State: github.String("open")})
+					return err
+				},
+			})
+		}
+
+		for _, other := range shadowing(theirs, group.Due, now) {
+			if *other.Title == group.Title || isGroup(groups, *other.Title) {
+				continue
+			}
+			logger.Warnf("%s won't be Current in %s, %s is due sooner (%s)", group.Title, project, *other.Title, dueDate(*other.DueOn))
+		}
+	}
+	return plan
+}
+
+// findMilestone by title, whatever its state
+func findMilestone(milestones []github.Milestone, title string) (github.Milestone, bool) {
+	for _, m := range milestones {
+		if *m.Title == title {
+			return m, true
+		}
+	}
+	return github.Milestone{}, false
+}
+
+// milestoneStatus is when a milestone is due, and whether it's closed
+func milestoneStatus(m github.Milestone) string {
+	due := "no due date"
+	if m.DueOn != nil {
+		due = fmt.Sprintf("due %s", dueDate(*m.DueOn))
+	}
+	if m.State != nil && *m.State != "open" {
+		return fmt.Sprintf("%s, %s", *m.State, due)
+	}
+	return due
+}
+
+// isGroup checks whether title is one of the milestones being synced, so
+// every project will have it and it isn't worth a warning
+func isGroup(groups []*MilestoneGroup, title string) bool {
+	for _, group := range groups {
+		if group.Title == title {
+			return true
+		}
+	}
+	return false
+}
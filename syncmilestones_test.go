@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// day n after the test's now, at noon like github has them
+func syncDay(n int) *time.Time {
+	t := time.Date(2016, 3, 1+n, 12, 0, 0, 0, time.UTC)
+	return &t
+}
+
+// syncMilestone is an open milestone unless a state is given
+func syncMilestone(number int, title string, due *time.Time, state ...string) github.Milestone {
+	m := github.Milestone{Number: github.Int(number), Title: github.String(title), DueOn: due, State: github.String("open")}
+	if len(state) > 0 {
+		m.State = github.String(state[0])
+	}
+	return m
+}
+
+func TestGroupMilestones(t *testing.T) {
+	now := *syncDay(0)
+	theirs := map[string][]github.Milestone{
+		"a/a": {syncMilestone(1, "W10", syncDay(6)), syncMilestone(2, "W11", syncDay(13)), syncMilestone(3, "W9", syncDay(-1))},
+		"a/b": {syncMilestone(1, "W10", syncDay(6)), syncMilestone(2, "Hotfix", syncDay(2))},
+		"a/c": {syncMilestone(1, "W10", syncDay(7)), syncMilestone(2, "W11", syncDay(14)), syncMilestone(3, "W12", syncDay(20), "closed")},
+		"a/d": {syncMilestone(1, "Someday", nil)},
+	}
+
+	groups := groupMilestones(theirs, now)
+	got := []string{}
+	for _, group := range groups {
+		got = append(got, group.Title+" "+dueDate(group.Due))
+	}
+	// most projects win, then the earliest date
+	expected := "Hotfix 2016-03-03, W10 2016-03-07, W11 2016-03-14"
+	if strings.Join(got, ", ") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, ", "))
+	}
+
+	shared := sharedGroups(groups)
+	got = []string{}
+	for _, group := range shared {
+		got = append(got, group.Title)
+	}
+	if strings.Join(got, ", ") != "W10, W11" {
+		t.Errorf("expected W10 and W11 to be shared, got %s", strings.Join(got, ", "))
+	}
+
+	// Hotfix is sooner in a/b, the past due and closed ones don't count
+	milestones := append(theirs["a/b"], syncMilestone(3, "Old", syncDay(-2)), syncMilestone(4, "Done", syncDay(3), "closed"))
+	shadows := shadowing(milestones, shared[0].Due, now)
+	if len(shadows) != 1 || *shadows[0].Title != "Hotfix" {
+		t.Errorf("expected only Hotfix to shadow W10, got %v", shadows)
+	}
+	if shadows := shadowing(theirs["a/a"], shared[0].Due, now); len(shadows) != 0 {
+		t.Errorf("expected nothing to shadow W10 in a/a, got %v", shadows)
+	}
+}
+
+func TestPlanMilestoneSync(t *testing.T) {
+	now := *syncDay(0)
+	groups := []*MilestoneGroup{{
+		Title: "W10",
+		Due:   *syncDay(6),
+		Projects: map[string]github.Milestone{
+			"a/a": syncMilestone(1, "W10", syncDay(6)),
+			"a/c": syncMilestone(1, "W10", syncDay(7)),
+		},
+	}}
+
+	tests := []struct {
+		project  string
+		theirs   []github.Milestone
+		expected string
+	}{
+		{"a/a", []github.Milestone{syncMilestone(1, "W10", syncDay(6))}, ""},
+		{"a/b", nil, "create milestone W10 (due 2016-03-07)"},
+		{"a/c", []github.Milestone{syncMilestone(1, "W10", syncDay(7))}, "update milestone W10: due 2016-03-08 -> due 2016-03-07"},
+		// these aren't in the group, but they're there
+		{"a/d", []github.Milestone{syncMilestone(5, "W10", syncDay(6), "closed")}, "update milestone W10: closed, due 2016-03-07 -> due 2016-03-07"},
+		{"a/e", []github.Milestone{syncMilestone(5, "W10", syncDay(-7))}, "update milestone W10: due 2016-02-23 -> due 2016-03-07"},
+		{"a/f", []github.Milestone{syncMilestone(5, "W10", nil)}, "update milestone W10: no due date -> due 2016-03-07"},
+	}
+	for _, test := range tests {
+		plan := planMilestoneSync(nil, test.project, "a", test.project[2:], test.theirs, groups, now)
+		changes := []string{}
+		for _, change := range plan.Changes {
+			changes = append(changes, change.String())
+		}
+		if got := strings.Join(changes, "; "); got != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.project, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Edit is one change the menus made, to one or more issues, kept so it
+// can be undone
+type Edit struct {
+	What    string
+	Changes []*IssueChange
+}
+
+// IssueChange is what an issue was before and after an Edit
+type IssueChange struct {
+	Issue  *Issue
+	Before IssueState
+	After  IssueState
+}
+
+// IssueState is the part of an issue the menus change
+type IssueState struct {
+	Milestone  *IssueMilestone
+	Dimensions []*IssueLabel
+	Labels     []string
+	Assignees  []string
+}
+
+// stateOf an issue, copied so later changes don't touch it
+func stateOf(issue *Issue) IssueState {
+	return IssueState{
+		Milestone:  issue.Milestone,
+		Dimensions: append([]*IssueLabel{}, issue.Dimensions...),
+		Labels:     append([]string{}, issue.Labels...),
+		Assignees:  append([]string{}, issue.Assignees...),
+	}
+}
+
+// milestoneNumber of an issue's milestone, 0 if it has none
+func milestoneNumber(m *IssueMilestone) int {
+	if m == nil || m.Milestone == nil {
+		return 0
+	}
+	return m.Number
+}
+
+// sameLabels checks two sets of labels, ignoring the order
+func sameLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := map[string]int{}
+	for _, l := range a {
+		counts[l]++
+	}
+	for _, l := range b {
+		counts[l]--
+		if counts[l] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// pushEdit onto the undo stack, anything undone before can't be redone
+// anymore
+func (w *ListWindow) pushEdit(edit *Edit) {
+	if len(edit.Changes) == 0 {
+		return
+	}
+	w.undos = append(w.undos, edit)
+	w.redos = nil
+	w.notice = ""
+}
+
+// undo the last edit on github too, whatever fails to undo stays on the
+// undo stack to try again
+func (w *ListWindow) undo() {
+	if len(w.undos) == 0 {
+		w.notice = "nothing to undo"
+		return
+	}
+	edit := w.undos[len(w.undos)-1]
+	w.undos = w.undos[:len(w.undos)-1]
+	done, left := w.revert(fmt.Sprintf("Undoing %s", edit.What), edit, true)
+	if left != nil {
+		w.undos = append(w.undos, left)
+	}
+	if done == nil {
+		w.notice = fmt.Sprintf("couldn't undo %s", describeEdit(edit))
+		return
+	}
+	w.redos = append(w.redos, done)
+	w.notice = fmt.Sprintf("undid %s", describeEdit(done))
+}
+
+// redo the last undone edit, whatever fails to redo stays on the redo
+// stack
+func (w *ListWindow) redo() {
+	if len(w.redos) == 0 {
+		w.notice = "nothing to redo"
+		return
+	}
+	edit := w.redos[len(w.redos)-1]
+	w.redos = w.redos[:len(w.redos)-1]
+	done, left := w.revert(fmt.Sprintf("Redoing %s", edit.What), edit, false)
+	if left != nil {
+		w.redos = append(w.redos, left)
+	}
+	if done == nil {
+		w.notice = fmt.Sprintf("couldn't redo %s", describeEdit(edit))
+		return
+	}
+	w.undos = append(w.undos, done)
+	w.notice = fmt.Sprintf("redid %s", describeEdit(done))
+}
+
+// revert the issues in an edit to before it (or after it when redoing),
+// splitting it into the changes that went through and the ones that
+// didn't, either of which is nil if there aren't any
+func (w *ListWindow) revert(doing string, edit *Edit, undo bool) (*Edit, *Edit) {
+	issues := []*Issue{}
+	changes := map[*Issue]*IssueChange{}
+	for _, c := range edit.Changes {
+		// a refresh since the edit will have swapped out the issue
+		issue := w.issueByURL(c.Issue.URL)
+		if issue == nil {
+			issue = c.Issue
+		}
+		issues = append(issues, issue)
+		changes[issue] = c
+	}
+	failed := w.each(doing, issues, func(issue *Issue) error {
+		c := changes[issue]
+		if undo {
+			return w.setState(issue, c.After, c.Before)
+		}
+		return w.setState(issue, c.Before, c.After)
+	})
+
+	done := &Edit{What: edit.What}
+	left := &Edit{What: edit.What}
+	for _, issue := range issues {
+		if containsIssue(failed, issue) {
+			left.Changes = append(left.Changes, changes[issue])
+		} else {
+			done.Changes = append(done.Changes, changes[issue])
+		}
+	}
+	if len(done.Changes) == 0 {
+		done = nil
+	}
+	if len(left.Changes) == 0 {
+		left = nil
+	}
+	return done, left
+}
+
+// containsIssue checks for an issue in a list of them
+func containsIssue(issues []*Issue, issue *Issue) bool {
+	for _, i := range issues {
+		if i == issue {
+			return true
+		}
+	}
+	return false
+}
+
+// setState takes an issue from one state to another on github, then on
+// the issue, only touching what differs between the two so that anything
+// somebody else changed since is left alone
+func (w *ListWindow) setState(issue *Issue, from, to IssueState) error {
+	if milestoneNumber(from.Milestone) != milestoneNumber(to.Milestone) && milestoneNumber(issue.Milestone) != milestoneNumber(to.Milestone) {
+		err := w.API.SetMilestone(issue, to.Milestone.Milestone)
+		if err != nil {
+			return err
+		}
+		issue.Milestone = to.Milestone
+	}
+	if labels := moveLabels(issue.Labels, from.Labels, to.Labels, false); !sameLabels(issue.Labels, labels) {
+		err := w.API.ReplaceLabels(issue, labels)
+		if err != nil {
+			return err
+		}
+		issue.Labels = labels
+	}
+	if logins := moveLabels(issue.Assignees, from.Assignees, to.Assignees, true); !sameLabels(issue.Assignees, logins) {
+		err := w.API.SetAssignees(issue, logins)
+		if err != nil {
+			return err
+		}
+		issue.Assignees = logins
+	}
+	for i := range issue.Dimensions {
+		if i < len(from.Dimensions) && i < len(to.Dimensions) && from.Dimensions[i].Index != to.Dimensions[i].Index {
+			issue.Dimensions[i] = to.Dimensions[i]
+		}
+	}
+	return nil
+}
+
+// moveLabels takes whatever is in from but not to out of labels, and adds
+// whatever is in to but not from, logins aren't case sensitive
+func moveLabels(labels, from, to []string, logins bool) []string {
+	has := func(ls []string, l string) bool {
+		for _, x := range ls {
+			if x == l || (logins && strings.EqualFold(x, l)) {
+				return true
+			}
+		}
+		return false
+	}
+	out := []string{}
+	for _, l := range labels {
+		if !has(from, l) || has(to, l) {
+			out = append(out, l)
+		}
+	}
+	for _, l := range to {
+		if !has(from, l) && !has(out, l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// issueByURL finds an issue in the list
+func (w *ListWindow) issueByURL(url string) *Issue {
+	for _, issue := range w.issues {
+		if issue.URL == url {
+			return issue
+		}
+	}
+	return nil
+}
+
+// describeEdit for the status line
+func describeEdit(edit *Edit) string {
+	issues := []*Issue{}
+	for _, c := range edit.Changes {
+		issues = append(issues, c.Issue)
+	}
+	return fmt.Sprintf("%s on %s", edit.What, describeIssues(issues))
+}
+
+// describeIssues briefly, all of them by number if there's more than one
+func describeIssues(issues []*Issue) string {
+	if len(issues) == 1 {
+		return fmt.Sprintf("%s/%s#%d", issues[0].Owner, issues[0].Repo, issues[0].Number)
+	}
+	refs := []string{}
+	for _, issue := range issues {
+		refs = append(refs, fmt.Sprintf("#%d", issue.Number))
+	}
+	return fmt.Sprintf("%d issues (%s)", len(issues), strings.Join(refs, " "))
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingAPI can't change the labels of some issues
+type failingAPI struct {
+	*FakeAPI
+	failing map[string]bool
+}
+
+// ReplaceLabels unless the issue is failing
+func (a *failingAPI) ReplaceLabels(issue *Issue, labels []string) error {
+	if a.failing[issue.URL] {
+		return fmt.Errorf("Not today")
+	}
+	return a.FakeAPI.ReplaceLabels(issue, labels)
+}
+
+// press keys in a ui showing the fixture, the caller restores the screen
+func press(t *testing.T, w *TopIssueWindow, keys string) {
+	events, err := parseKeys(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range events {
+		if _, err := w.HandleEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+		w.Redraw()
+		w.Wait()
+	}
+}
+
+// testUI on a CellScreen, with the fixture loaded
+func testUI(t *testing.T, api API) (*TopIssueWindow, *ListWindow) {
+	screen = NewCellScreen(100, 16)
+	w := NewTopIssueWindow(testOptions(), testConfig(), api, "")
+	if err := w.Init(); err != nil {
+		t.Fatal(err)
+	}
+	w.Wait()
+	w.Redraw()
+	return w, w.List.(*ListWindow)
+}
+
+func TestUndoLeavesOtherChanges(t *testing.T) {
+	defer func() { screen = termboxScreen{} }()
+	api, err := LoadFakeAPI(filepath.Join("testdata", "raw_issues.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, list := testUI(t, api)
+
+	// foo#12 goes from bug to task, then somebody else labels it
+	press(t, w, "<down>t2")
+	issue := list.issueByURL("https://github.com/wercker/foo/issues/12")
+	issue.Labels = append(issue.Labels, "help wanted")
+	api.Calls = nil
+
+	press(t, w, "u")
+	expected := "ReplaceLabels wercker/foo#12 critical,help wanted,bug"
+	if calls := strings.Join(api.Calls, "; "); calls != expected {
+		t.Errorf("expected %q, got %q", expected, calls)
+	}
+}
+
+func TestUndoKeepsWhatFailed(t *testing.T) {
+	defer func() { screen = termboxScreen{} }()
+	fake, err := LoadFakeAPI(filepath.Join("testdata", "raw_issues.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &failingAPI{fake, map[string]bool{}}
+	w, list := testUI(t, api)
+
+	// bar#40 and foo#12 go to task, and only foo#12 comes back
+	press(t, w, "<space><down><space>t2")
+	api.failing["https://github.com/wercker/bar/issues/40"] = true
+	fake.Calls = nil
+	press(t, w, "u")
+
+	if len(list.undos) != 1 || len(list.undos[0].Changes) != 1 || list.undos[0].Changes[0].Issue.Number != 40 {
+		t.Fatalf("expected bar#40 left to undo, got %+v", list.undos)
+	}
+	if len(list.redos) != 1 || len(list.redos[0].Changes) != 1 || list.redos[0].Changes[0].Issue.Number != 12 {
+		t.Fatalf("expected foo#12 to redo, got %+v", list.redos)
+	}
+	if list.notice != "undid type task on wercker/foo#12" {
+		t.Errorf("wrong notice: %s", list.notice)
+	}
+
+	// dismiss the failure and try again
+	delete(api.failing, "https://github.com/wercker/bar/issues/40")
+	press(t, w, "<esc>u")
+	if len(list.undos) != 0 || len(list.redos) != 2 {
+		t.Errorf("expected everything undone, got %d undos and %d redos", len(list.undos), len(list.redos))
+	}
+	expected := "ReplaceLabels wercker/foo#12 critical,bug; ReplaceLabels wercker/bar#40 low,bug"
+	if calls := strings.Join(fake.Calls, "; "); calls != expected {
+		t.Errorf("expected %q, got %q", expected, calls)
+	}
+
+	// nothing went through at all
+	api.failing["https://github.com/wercker/bar/issues/40"] = true
+	press(t, w, "<c-r>")
+	if len(list.undos) != 0 || len(list.redos) != 2 || !strings.HasPrefix(list.notice, "couldn't redo") {
+		t.Errorf("expected the redo to stay put, got %d undos, %d redos and %q", len(list.undos), len(list.redos), list.notice)
+	}
+}
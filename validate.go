@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/codegangsta/cli"
+	"github.com/google/go-github/github"
+)
+
+var (
+	validateConfigCommand = cli.Command{
+		Name:  "validate-config",
+		Usage: "check the config and that your projects are set up for it",
+		Action: func(c *cli.Context) {
+			opts, err := NewOptions(c)
+			if err != nil {
+				logger.Errorln("Invalid options", err)
+				os.Exit(1)
+			}
+			err = cmdValidateConfig(opts)
+			if err != nil {
+				SoftExit(opts, err)
+			}
+		},
+	}
+)
+
+var colorRegexp = regexp.MustCompile("^[0-9a-fA-F]{6}$")
+
+// reservedKeys are taken by the ui, so no dimension or milestone tier can
+// use them
+var reservedKeys = "ms/?:* uca"
+
+// detailKeys are taken by the whole-issue view before any reply
+var detailKeys = "jkgGq"
+
+// Report collects the results of a bunch of checks
+type Report struct {
+	Failures int
+}
+
+// Section starts a new group of checks
+func (r *Report) Section(name string) {
+	fmt.Printf("%s:\n", name)
+}
+
+// Check prints a passing check, or a failing one if err isn't nil
+func (r *Report) Check(what string, err error) {
+	if err != nil {
+		r.Failures++
+		fmt.Printf("  FAIL %s: %s\n", what, err)
+		return
+	}
+	fmt.Printf("    ok %s\n", what)
+}
+
+// validateLabels checks the labels in the config make sense on their own
+func validateLabels(config *Config) []error {
+	errs := []error{}
+	seen := map[string]string{}
+	check := func(kind string, label Label) {
+		if label.Name == "" {
+			errs = append(errs, fmt.Errorf("a %s has no name", kind))
+			return
+		}
+		if !colorRegexp.MatchString(label.Color) {
+			errs = append(errs, fmt.Errorf("%s %q has a bad color %q, expected something like e11d21", kind, label.Name, label.Color))
+		}
+		// github doesn't care about case
+		for _, name := range append([]string{label.Name}, label.Aliases...) {
+			key := strings.ToLower(name)
+			if other, ok := seen[key]; ok {
+				errs = append(errs, fmt.Errorf("%s %q is already a %s", kind, name, other))
+			}
+			seen[key] = kind
+		}
+	}
+	for _, d := range config.Dimensions {
+		for _, label := range d.Labels {
+			check(d.Name, label)
+		}
+	}
+	return errs
+}
+
+// validateDimensions checks each dimension has a name and a hotkey of its
+// own
+func validateDimensions(config *Config) []error {
+	errs := []error{}
+	names := map[string]bool{}
+	keys := map[rune]string{}
+	for _, d := range config.Dimensions {
+		if d.Name == "" {
+			errs = append(errs, fmt.Errorf("a dimension has no name"))
+			continue
+		}
+		if names[strings.ToLower(d.Name)] {
+			errs = append(errs, fmt.Errorf("dimension %q is there twice", d.Name))
+		}
+		names[strings.ToLower(d.Name)] = true
+		if len(d.Labels) > 9 {
+			errs = append(errs, fmt.Errorf("dimension %q has %d labels, the menu only goes up to 9", d.Name, len(d.Labels)))
+		}
+
+		key := d.Key()
+		if len([]rune(d.Hotkey)) != 1 {
+			errs = append(errs, fmt.Errorf("dimension %q needs a single character hotkey, not %q", d.Name, d.Hotkey))
+			continue
+		}
+		if strings.ContainsRune(reservedKeys, key) || unicode.IsDigit(key) {
+			errs = append(errs, fmt.Errorf("dimension %q can't use hotkey %q, the ui uses it", d.Name, d.Hotkey))
+		}
+		if other, ok := keys[key]; ok {
+			errs = append(errs, fmt.Errorf("dimension %q has the same hotkey as %q", d.Name, other))
+		}
+		keys[key] = d.Name
+	}
+	return errs
+}
+
+// validateMilestoneTiers checks each tier has a name, a hotkey of its own
+// and an index that fits in the idx
+func validateMilestoneTiers(config *Config) []error {
+	errs := []error{}
+	names := map[string]bool{}
+	keys := map[rune]string{}
+	for _, d := range config.Dimensions {
+		keys[d.Key()] = d.Name
+	}
+	for _, tier := range config.MilestoneTiers {
+		if tier.Name == "" {
+			errs = append(errs, fmt.Errorf("a milestone tier has no name"))
+			continue
+		}
+		if names[strings.ToLower(tier.Name)] {
+			errs = append(errs, fmt.Errorf("milestone tier %q is there twice", tier.Name))
+		}
+		names[strings.ToLower(tier.Name)] = true
+		if tier.Index < 1 || tier.Index > 9 {
+			errs = append(errs, fmt.Errorf("milestone tier %q has index %d, it needs to be 1 to 9", tier.Name, tier.Index))
+		}
+
+		key := tier.Key()
+		if len([]rune(tier.Hotkey)) != 1 {
+			errs = append(errs, fmt.Errorf("milestone tier %q needs a single character hotkey, not %q", tier.Name, tier.Hotkey))
+			continue
+		}
+		if strings.ContainsRune(reservedKeys, key) {
+			errs = append(errs, fmt.Errorf("milestone tier %q can't use hotkey %q, the ui uses it", tier.Name, tier.Hotkey))
+		}
+		if other, ok := keys[key]; ok {
+			errs = append(errs, fmt.Errorf("milestone tier %q has the same hotkey as %q", tier.Name, other))
+		}
+		keys[key] = tier.Name
+	}
+	return errs
+}
+
+// validateReplies checks each canned reply has something to say and a
+// hotkey that the list menu won't take first
+func validateReplies(config *Config) []error {
+	errs := []error{}
+	keys := map[rune]string{}
+	for _, d := range config.Dimensions {
+		keys[d.Key()] = fmt.Sprintf("dimension %q", d.Name)
+	}
+	for i, reply := range config.Replies {
+		name := reply.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+			errs = append(errs, fmt.Errorf("reply %s has no name", name))
+		}
+		if strings.TrimSpace(reply.Body) == "" {
+			errs = append(errs, fmt.Errorf("reply %q has no body", name))
+		}
+
+		key := reply.Key()
+		if len([]rune(reply.Hotkey)) != 1 {
+			errs = append(errs, fmt.Errorf("reply %q needs a single character hotkey, not %q", name, reply.Hotkey))
+			continue
+		}
+		if strings.ContainsRune(reservedKeys, key) || strings.ContainsRune(detailKeys, key) {
+			errs = append(errs, fmt.Errorf("reply %q can't use hotkey %q, the ui uses it", name, reply.Hotkey))
+		}
+		if other, ok := keys[key]; ok {
+			errs = append(errs, fmt.Errorf("reply %q has the same hotkey as %s", name, other))
+		}
+		keys[key] = fmt.Sprintf("reply %q", name)
+	}
+	return errs
+}
+
+// validateForUI checks the hotkeys and menus, the ui would quietly lose a
+// menu to a clash
+func validateForUI(config *Config) error {
+	errs := validateDimensions(config)
+	errs = append(errs, validateMilestoneTiers(config)...)
+	errs = append(errs, validateReplies(config)...)
+	if len(errs) == 0 {
+		return nil
+	}
+	problems := []string{}
+	for _, err := range errs {
+		problems = append(problems, err.Error())
+	}
+	return fmt.Errorf("Config is not valid, see validate-config: %s", strings.Join(problems, "; "))
+}
+
+// cmdValidateConfig checks every config file strictly, then checks each
+// project against the config, failing if anything is wrong
+func cmdValidateConfig(opts *Options) error {
+	report := &Report{}
+
+	report.Section("config")
+	paths, err := configPaths(opts.ConfigFile)
+	report.Check("finding config files", err)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = yaml.UnmarshalStrict(data, &Config{})
+		}
+		report.Check(path, err)
+	}
+
+	config, err := LoadConfig(opts)
+	if err != nil {
+		report.Check("loading config", err)
+		return fmt.Errorf("Config is not valid")
+	}
+
+	dimensionErrs := validateDimensions(config)
+	for _, err := range dimensionErrs {
+		report.Check("dimensions", err)
+	}
+	if len(dimensionErrs) == 0 {
+		report.Check("dimensions", nil)
+	}
+
+	tierErrs := validateMilestoneTiers(config)
+	for _, err := range tierErrs {
+		report.Check("milestone tiers", err)
+	}
+	if len(tierErrs) == 0 {
+		report.Check("milestone tiers", nil)
+	}
+
+	_, err = config.Cadence.Schedule()
+	report.Check("cadence", err)
+
+	replyErrs := validateReplies(config)
+	for _, err := range replyErrs {
+		report.Check("replies", err)
+	}
+	if len(replyErrs) == 0 {
+		report.Check("replies", nil)
+	}
+
+	labelErrs := validateLabels(config)
+	for _, err := range labelErrs {
+		report.Check("labels", err)
+	}
+	if len(labelErrs) == 0 {
+		report.Check("labels", nil)
+	}
+	if len(config.AllProjects()) == 0 {
+		report.Check("projects", fmt.Errorf("no projects configured"))
+	}
+
+	api, err := NewAPI(opts, config)
+	if err != nil {
+		report.Check("api", err)
+		return fmt.Errorf("Config is not valid")
+	}
+	clients := NewGithubClients(opts, config)
+
+	for _, project := range config.Projects {
+		report.Section(project)
+		validateProject(report, clients, config, project)
+		_, err := api.Milestones(project)
+		report.Check("a milestone for each tier", err)
+	}
+	// the labels on GitLab aren't ours to check yet, but milestones are
+	for _, project := range config.Gitlab.Projects {
+		report.Section(project)
+		_, err := api.Milestones(project)
+		report.Check("a milestone for each tier", err)
+	}
+
+	if report.Failures > 0 {
+		return fmt.Errorf("%d problems found", report.Failures)
+	}
+	fmt.Println("All good")
+	return nil
+}
+
+// validateProject checks a GitHub project exists and has our labels
+func validateProject(report *Report, clients *GithubClients, config *Config, project string) {
+	client, owner, repo, err := clients.For(project)
+	if err != nil {
+		report.Check("project name", err)
+		return
+	}
+
+	_, _, err = client.Repositories.Get(owner, repo)
+	if err != nil {
+		report.Check("exists and is accessible", err)
+		return
+	}
+	report.Check("exists and is accessible", nil)
+
+	theirs := map[string]bool{}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		labels, resp, err := client.Issues.ListLabels(owner, repo, opt)
+		if err != nil {
+			report.Check("labels", err)
+			return
+		}
+		for _, label := range labels {
+			theirs[strings.ToLower(*label.Name)] = true
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	missing := []string{}
+	for _, label := range config.Labels() {
+		if !theirs[strings.ToLower(label.Name)] {
+			missing = append(missing, label.Name)
+		}
+	}
+	if len(missing) > 0 {
+		report.Check("labels", fmt.Errorf("missing %s, try set-labels", strings.Join(missing, ", ")))
+		return
+	}
+	report.Check("labels", nil)
+}
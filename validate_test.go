@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateReplies(t *testing.T) {
+	tests := []struct {
+		reply Reply
+		err   string
+	}{
+		{Reply{Name: "logs", Hotkey: "1", Body: "Logs?"}, ""},
+		{Reply{Name: "dupe", Hotkey: "d", Body: "Dupe"}, ""},
+		{Reply{Hotkey: "1", Body: "Logs?"}, "has no name"},
+		{Reply{Name: "logs", Hotkey: "1", Body: " "}, "has no body"},
+		{Reply{Name: "logs", Hotkey: "12", Body: "Logs?"}, "single character"},
+		{Reply{Name: "logs", Hotkey: "c", Body: "Logs?"}, "the ui uses it"},
+		{Reply{Name: "logs", Hotkey: "j", Body: "Logs?"}, "the ui uses it"},
+		{Reply{Name: "logs", Hotkey: "G", Body: "Logs?"}, "the ui uses it"},
+		{Reply{Name: "logs", Hotkey: "q", Body: "Logs?"}, "the ui uses it"},
+		{Reply{Name: "logs", Hotkey: "p", Body: "Logs?"}, "same hotkey as dimension"},
+	}
+	for _, test := range tests {
+		config := testConfig()
+		config.Replies = []Reply{test.reply}
+		errs := validateReplies(config)
+		if test.err == "" {
+			if len(errs) > 0 {
+				t.Errorf("%+v: unexpected errors %v", test.reply, errs)
+			}
+			continue
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), test.err) {
+			t.Errorf("%+v: expected an error containing %q, got %v", test.reply, test.err, errs)
+		}
+	}
+}